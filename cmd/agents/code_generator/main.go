@@ -7,105 +7,73 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
-	"runtime"
-	"runtime/debug"
-	"time"
 
+	"github.com/natalie/go-flags-eval/internal/agentharness"
 	"github.com/natalie/go-flags-eval/internal/agentmetrics"
 )
 
 var (
-	numFiles      = flag.Int("files", 10, "Number of files to generate")
-	numLines      = flag.Int("lines", 100, "Number of lines per file")
-	outputDir     = flag.String("output", "./generated", "Output directory")
-	metricsOutput = flag.String("metrics-output", "", "File to write performance metrics (JSON)")
+	numFiles  = flag.Int("files", 10, "Number of files to generate")
+	numLines  = flag.Int("lines", 100, "Number of lines per file")
+	outputDir = flag.String("output", "./generated", "Output directory")
 )
 
-func main() {
-	flag.Parse()
+// codeGenerator writes -files files of -lines lines each on every pass.
+// Each pass overwrites the files of the previous one.
+type codeGenerator struct {
+	successCount int // Of the last pass
+}
 
-	start := time.Now()
+func main() {
+	agentharness.Main(agentharness.Agent{
+		Title:    "Code Generator Agent",
+		Workload: &codeGenerator{},
+	})
+}
 
-	// Create output directory
-	if err := os.MkdirAll(*outputDir, 0755); err != nil {
-		log.Fatalf("Failed to create output directory: %v", err)
+func (g *codeGenerator) Describe() []string {
+	return []string{
+		fmt.Sprintf("Files to generate: %d", *numFiles),
+		fmt.Sprintf("Lines per file: %d", *numLines),
 	}
+}
 
-	// Report configuration
-	fmt.Printf("Code Generator Agent\n")
-	fmt.Printf("====================\n")
-	fmt.Printf("GOMAXPROCS: %d\n", runtime.GOMAXPROCS(-1))
-	fmt.Printf("GOGC: %d\n", debug.SetGCPercent(-1))
-	debug.SetGCPercent(debug.SetGCPercent(-1)) // Restore
-	fmt.Printf("Files to generate: %d\n", *numFiles)
-	fmt.Printf("Lines per file: %d\n", *numLines)
-	fmt.Printf("\n")
-
-	// Generate files concurrently
-	type result struct {
-		filename string
-		err      error
+// Setup creates the output directory
+func (g *codeGenerator) Setup(env *agentharness.Env) error {
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
 	}
+	return nil
+}
 
-	results := make(chan result, *numFiles)
-	sem := make(chan struct{}, runtime.GOMAXPROCS(-1))
-
-	for i := 0; i < *numFiles; i++ {
-		go func(fileNum int) {
-			sem <- struct{}{}
-			defer func() { <-sem }()
+func (g *codeGenerator) Run(env *agentharness.Env) error {
+	defer env.Phase("generate")()
 
-			filename := filepath.Join(*outputDir, fmt.Sprintf("generated_%d.go", fileNum))
-			err := generateGoFile(filename, *numLines)
-			results <- result{filename, err}
-		}(i)
-	}
+	filenames := make([]string, *numFiles)
+	errs := make([]error, *numFiles)
+	env.ForEach(*numFiles, func(i int) {
+		filenames[i] = filepath.Join(*outputDir, fmt.Sprintf("generated_%d.go", i))
+		errs[i] = generateGoFile(filenames[i], *numLines, env.Rand(i))
+	})
 
 	// Collect results
-	successCount := 0
-	for i := 0; i < *numFiles; i++ {
-		res := <-results
-		if res.err != nil {
-			log.Printf("Error generating %s: %v", res.filename, res.err)
+	g.successCount = 0
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("Error generating %s: %v", filenames[i], err)
 		} else {
-			successCount++
+			g.successCount++
 		}
 	}
+	return nil
+}
 
-	elapsed := time.Since(start)
-
-	// Collect statistics
-	var ms runtime.MemStats
-	runtime.ReadMemStats(&ms)
-
-	// Print statistics
-	fmt.Printf("\nResults:\n")
-	fmt.Printf("========\n")
-	fmt.Printf("Files generated: %d/%d\n", successCount, *numFiles)
-	fmt.Printf("Duration: %v\n", elapsed)
-	fmt.Printf("Memory allocated: %.2f MB\n", float64(ms.TotalAlloc)/(1024*1024))
-	fmt.Printf("GC runs: %d\n", ms.NumGC)
-	fmt.Printf("Goroutines: %d\n", runtime.NumGoroutine())
-
-	// Write metrics to file if requested
-	if *metricsOutput != "" {
-		metrics := &agentmetrics.Metrics{
-			Duration:        elapsed,
-			MemoryAllocated: ms.TotalAlloc,
-			HeapAllocated:   ms.HeapAlloc,
-			NumGC:           ms.NumGC,
-			PauseTimeNs:     ms.PauseTotalNs,
-			Goroutines:      runtime.NumGoroutine(),
-			TasksCompleted:  successCount,
-		}
-
-		if err := metrics.WriteToFile(*metricsOutput); err != nil {
-			log.Printf("Failed to write metrics: %v", err)
-		}
-	}
+func (g *codeGenerator) Report(metrics *agentmetrics.Metrics) {
+	metrics.TasksCompleted = g.successCount
+	fmt.Printf("Files generated: %d/%d\n", g.successCount, *numFiles)
 }
 
-func generateGoFile(filename string, lines int) error {
+func generateGoFile(filename string, lines int, rng *rand.Rand) error {
 	f, err := os.Create(filename)
 	if err != nil {
 		return err
@@ -135,7 +103,7 @@ func generateGoFile(filename string, lines int) error {
 		// Generate function body
 		linesInFunc := lines / numFuncs
 		for j := 0; j < linesInFunc; j++ {
-			operation := rand.Intn(4)
+			operation := rng.Intn(4)
 			switch operation {
 			case 0:
 				fmt.Fprintf(f, "\tx = x + y\n")
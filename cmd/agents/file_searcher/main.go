@@ -4,23 +4,16 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
-	"log"
 	"os"
-	"path/filepath"
-	"runtime"
-	"runtime/debug"
 	"strings"
-	"sync"
-	"time"
 
+	"github.com/natalie/go-flags-eval/internal/agentharness"
 	"github.com/natalie/go-flags-eval/internal/agentmetrics"
 )
 
 var (
-	pattern       = flag.String("pattern", "func", "Pattern to search for")
-	dir           = flag.String("dir", "./testdata", "Directory to search in")
-	workers       = flag.Int("workers", 0, "Number of worker goroutines (0 = GOMAXPROCS)")
-	metricsOutput = flag.String("metrics-output", "", "File to write performance metrics (JSON)")
+	pattern = flag.String("pattern", "func", "Pattern to search for")
+	dir     = flag.String("dir", "./testdata", "Directory to search in")
 )
 
 type Match struct {
@@ -29,136 +22,80 @@ type Match struct {
 	Content string
 }
 
-func main() {
-	flag.Parse()
+// fileSearcher greps every Go file below -dir for -pattern on each pass
+type fileSearcher struct {
+	files   []string // Of the last pass
+	matches []Match
+}
 
-	start := time.Now()
+func main() {
+	agentharness.Main(agentharness.Agent{
+		Title:    "File Searcher Agent",
+		Workload: &fileSearcher{},
+	})
+}
 
-	if *workers == 0 {
-		*workers = runtime.GOMAXPROCS(-1)
+func (s *fileSearcher) Describe() []string {
+	return []string{
+		fmt.Sprintf("Pattern: %s", *pattern),
+		fmt.Sprintf("Directory: %s", *dir),
 	}
+}
 
-	// Report configuration
-	fmt.Printf("File Searcher Agent\n")
-	fmt.Printf("===================\n")
-	fmt.Printf("GOMAXPROCS: %d\n", runtime.GOMAXPROCS(-1))
-	fmt.Printf("GOGC: %d\n", debug.SetGCPercent(-1))
-	debug.SetGCPercent(debug.SetGCPercent(-1)) // Restore
-	fmt.Printf("Pattern: %s\n", *pattern)
-	fmt.Printf("Directory: %s\n", *dir)
-	fmt.Printf("Workers: %d\n", *workers)
-	fmt.Printf("\n")
-
+func (s *fileSearcher) Run(env *agentharness.Env) error {
 	// Find all Go files
-	var files []string
-	err := filepath.Walk(*dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && strings.HasSuffix(path, ".go") {
-			files = append(files, path)
-		}
-		return nil
-	})
-
+	stop := env.Phase("walk")
+	files, err := agentharness.FindGoFiles(*dir)
+	stop()
 	if err != nil {
-		log.Fatalf("Failed to walk directory: %v", err)
+		return fmt.Errorf("failed to walk directory: %w", err)
 	}
 
-	fmt.Printf("Found %d Go files to search\n\n", len(files))
-
 	// Search files concurrently
-	fileChan := make(chan string, len(files))
-	matchChan := make(chan Match, 100)
-
-	var wg sync.WaitGroup
-
-	// Start workers
-	for i := 0; i < *workers; i++ {
-		wg.Add(1)
-		go worker(&wg, fileChan, matchChan, *pattern)
-	}
-
-	// Send files to workers
-	go func() {
-		for _, file := range files {
-			fileChan <- file
-		}
-		close(fileChan)
-	}()
-
-	// Collect matches
-	go func() {
-		wg.Wait()
-		close(matchChan)
-	}()
+	defer env.Phase("search")()
+	perFile := make([][]Match, len(files))
+	env.ForEach(len(files), func(i int) {
+		perFile[i] = searchFile(files[i], *pattern)
+	})
 
 	matches := []Match{}
-	for match := range matchChan {
-		matches = append(matches, match)
+	for _, m := range perFile {
+		matches = append(matches, m...)
 	}
+	s.files, s.matches = files, matches
+	return nil
+}
 
-	elapsed := time.Since(start)
-
-	// Print results
-	var ms runtime.MemStats
-	runtime.ReadMemStats(&ms)
+func (s *fileSearcher) Report(metrics *agentmetrics.Metrics) {
+	metrics.FilesProcessed = len(s.files)
+	metrics.Custom = map[string]any{
+		"matches_found": len(s.matches),
+	}
 
-	fmt.Printf("\nResults:\n")
-	fmt.Printf("========\n")
-	fmt.Printf("Files searched: %d\n", len(files))
-	fmt.Printf("Matches found: %d\n", len(matches))
-	fmt.Printf("Duration: %v\n", elapsed)
-	fmt.Printf("Memory allocated: %.2f MB\n", float64(ms.TotalAlloc)/(1024*1024))
-	fmt.Printf("GC runs: %d\n", ms.NumGC)
+	fmt.Printf("Files searched: %d\n", len(s.files))
+	fmt.Printf("Matches found: %d\n", len(s.matches))
 
 	// Print first 10 matches
-	if len(matches) > 0 {
+	if len(s.matches) > 0 {
 		fmt.Printf("\nFirst 10 matches:\n")
-		for i, match := range matches {
+		for i, match := range s.matches {
 			if i >= 10 {
 				break
 			}
 			fmt.Printf("%s:%d: %s\n", match.File, match.Line, strings.TrimSpace(match.Content))
 		}
-	}
-
-	// Write metrics to file if requested
-	if *metricsOutput != "" {
-		metrics := &agentmetrics.Metrics{
-			Duration:        elapsed,
-			MemoryAllocated: ms.TotalAlloc,
-			HeapAllocated:   ms.HeapAlloc,
-			NumGC:           ms.NumGC,
-			PauseTimeNs:     ms.PauseTotalNs,
-			Goroutines:      runtime.NumGoroutine(),
-			FilesProcessed:  len(files),
-			Custom: map[string]any{
-				"matches_found": len(matches),
-			},
-		}
-
-		if err := metrics.WriteToFile(*metricsOutput); err != nil {
-			log.Printf("Failed to write metrics: %v", err)
-		}
-	}
-}
-
-func worker(wg *sync.WaitGroup, files <-chan string, matches chan<- Match, pattern string) {
-	defer wg.Done()
-
-	for file := range files {
-		searchFile(file, pattern, matches)
+		fmt.Printf("\n")
 	}
 }
 
-func searchFile(filename, pattern string, matches chan<- Match) {
+func searchFile(filename, pattern string) []Match {
 	f, err := os.Open(filename)
 	if err != nil {
-		return
+		return nil
 	}
 	defer f.Close()
 
+	var matches []Match
 	scanner := bufio.NewScanner(f)
 	lineNum := 0
 
@@ -166,11 +103,12 @@ func searchFile(filename, pattern string, matches chan<- Match) {
 		lineNum++
 		line := scanner.Text()
 		if strings.Contains(line, pattern) {
-			matches <- Match{
+			matches = append(matches, Match{
 				File:    filename,
 				Line:    lineNum,
 				Content: line,
-			}
+			})
 		}
 	}
+	return matches
 }
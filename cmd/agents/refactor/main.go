@@ -6,130 +6,86 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
-	"runtime"
-	"runtime/debug"
 	"strings"
-	"sync"
-	"time"
 
+	"github.com/natalie/go-flags-eval/internal/agentharness"
 	"github.com/natalie/go-flags-eval/internal/agentmetrics"
 )
 
 var (
-	target        = flag.String("target", "./testdata", "Target directory for refactoring")
-	operation     = flag.String("operation", "rename", "Operation: rename, add-comments, format")
-	oldName       = flag.String("old", "oldVar", "Old variable name (for rename)")
-	newName       = flag.String("new", "newVar", "New variable name (for rename)")
-	metricsOutput = flag.String("metrics-output", "", "File to write performance metrics (JSON)")
+	target    = flag.String("target", "./testdata", "Target directory for refactoring")
+	operation = flag.String("operation", "rename", "Operation: rename, add-comments, format")
+	oldName   = flag.String("old", "oldVar", "Old variable name (for rename)")
+	newName   = flag.String("new", "newVar", "New variable name (for rename)")
 )
 
+// refactorer applies -operation to every Go file below -target on each
+// pass. A single pass writes the changes back. With -duration the files
+// are refactored in memory only, so that every pass finds the same
+// changes to make instead of re-scanning already refactored files.
+type refactorer struct {
+	files         int // Of the last pass
+	filesModified int // Over all passes
+	totalChanges  int
+}
+
 func main() {
-	flag.Parse()
-
-	start := time.Now()
-
-	// Report configuration
-	fmt.Printf("Refactor Agent\n")
-	fmt.Printf("==============\n")
-	fmt.Printf("GOMAXPROCS: %d\n", runtime.GOMAXPROCS(-1))
-	fmt.Printf("GOGC: %d\n", debug.SetGCPercent(-1))
-	debug.SetGCPercent(debug.SetGCPercent(-1)) // Restore
-	fmt.Printf("Target: %s\n", *target)
-	fmt.Printf("Operation: %s\n", *operation)
+	agentharness.Main(agentharness.Agent{
+		Title:    "Refactor Agent",
+		Workload: &refactorer{},
+	})
+}
+
+func (r *refactorer) Describe() []string {
+	lines := []string{
+		fmt.Sprintf("Target: %s", *target),
+		fmt.Sprintf("Operation: %s", *operation),
+	}
 	if *operation == "rename" {
-		fmt.Printf("Rename: %s -> %s\n", *oldName, *newName)
+		lines = append(lines, fmt.Sprintf("Rename: %s -> %s", *oldName, *newName))
 	}
-	fmt.Printf("\n")
+	return lines
+}
 
+func (r *refactorer) Run(env *agentharness.Env) error {
 	// Find all Go files
-	var files []string
-	err := filepath.Walk(*target, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && strings.HasSuffix(path, ".go") {
-			files = append(files, path)
-		}
-		return nil
-	})
-
+	stop := env.Phase("walk")
+	paths, err := agentharness.FindGoFiles(*target)
+	stop()
 	if err != nil {
-		log.Fatalf("Failed to walk directory: %v", err)
+		return fmt.Errorf("failed to walk directory: %w", err)
 	}
 
-	fmt.Printf("Found %d Go files to refactor\n\n", len(files))
-
 	// Refactor files concurrently
-	var wg sync.WaitGroup
-	results := make(chan refactorResult, len(files))
-	sem := make(chan struct{}, runtime.GOMAXPROCS(-1))
-
-	for _, file := range files {
-		wg.Add(1)
-		go func(filename string) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-
-			result := refactorFile(filename, *operation, *oldName, *newName)
-			results <- result
-		}(file)
-	}
-
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+	defer env.Phase("refactor")()
+	results := make([]refactorResult, len(paths))
+	env.ForEach(len(paths), func(i int) {
+		results[i] = refactorFile(paths[i], *operation, *oldName, *newName, !env.SteadyState)
+	})
 
 	// Collect results
-	totalChanges := 0
-	filesModified := 0
-
-	for result := range results {
+	for _, result := range results {
 		if result.err != nil {
 			log.Printf("Error processing %s: %v", result.filename, result.err)
 		} else if result.changes > 0 {
-			filesModified++
-			totalChanges += result.changes
+			r.filesModified++
+			r.totalChanges += result.changes
 		}
 	}
+	r.files = len(paths)
+	return nil
+}
 
-	elapsed := time.Since(start)
-
-	// Print statistics
-	var ms runtime.MemStats
-	runtime.ReadMemStats(&ms)
-
-	fmt.Printf("\nResults:\n")
-	fmt.Printf("========\n")
-	fmt.Printf("Files processed: %d\n", len(files))
-	fmt.Printf("Files modified: %d\n", filesModified)
-	fmt.Printf("Total changes: %d\n", totalChanges)
-	fmt.Printf("Duration: %v\n", elapsed)
-	fmt.Printf("Memory allocated: %.2f MB\n", float64(ms.TotalAlloc)/(1024*1024))
-	fmt.Printf("GC runs: %d\n", ms.NumGC)
-
-	// Write metrics to file if requested
-	if *metricsOutput != "" {
-		metrics := &agentmetrics.Metrics{
-			Duration:        elapsed,
-			MemoryAllocated: ms.TotalAlloc,
-			HeapAllocated:   ms.HeapAlloc,
-			NumGC:           ms.NumGC,
-			PauseTimeNs:     ms.PauseTotalNs,
-			Goroutines:      runtime.NumGoroutine(),
-			FilesProcessed:  len(files),
-			Custom: map[string]any{
-				"files_modified": filesModified,
-				"total_changes":  totalChanges,
-			},
-		}
-
-		if err := metrics.WriteToFile(*metricsOutput); err != nil {
-			log.Printf("Failed to write metrics: %v", err)
-		}
+func (r *refactorer) Report(metrics *agentmetrics.Metrics) {
+	metrics.FilesProcessed = r.files
+	metrics.Custom = map[string]any{
+		"files_modified": r.filesModified,
+		"total_changes":  r.totalChanges,
 	}
+
+	fmt.Printf("Files processed: %d\n", r.files)
+	fmt.Printf("Files modified: %d\n", r.filesModified)
+	fmt.Printf("Total changes: %d\n", r.totalChanges)
 }
 
 type refactorResult struct {
@@ -138,7 +94,9 @@ type refactorResult struct {
 	err      error
 }
 
-func refactorFile(filename, operation, oldName, newName string) refactorResult {
+// refactorFile applies operation to filename, writing the result back
+// only if write is set
+func refactorFile(filename, operation, oldName, newName string, write bool) refactorResult {
 	result := refactorResult{filename: filename}
 
 	// Read file
@@ -173,11 +131,14 @@ func refactorFile(filename, operation, oldName, newName string) refactorResult {
 		return result
 	}
 
-	// Write back if changes were made
+	// Write back if changes were made; the output is built either way so
+	// that in-memory passes do the same work short of the write
 	if result.changes > 0 {
 		output := strings.Join(lines, "\n") + "\n"
-		if err := os.WriteFile(filename, []byte(output), 0644); err != nil {
-			result.err = err
+		if write {
+			if err := os.WriteFile(filename, []byte(output), 0644); err != nil {
+				result.err = err
+			}
 		}
 	}
 
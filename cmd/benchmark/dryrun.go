@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/results"
+	"github.com/natalie/go-flags-eval/internal/runner"
+)
+
+// wallTimes collects the observed wall time of runs per task/config pair
+type wallTimes map[pairKey][]time.Duration
+
+// addResult records a finished run, including the staging of its input,
+// which happens outside the measured duration but still takes wall time
+func (w wallTimes) addResult(r runner.BenchmarkResult) {
+	wall := r.Duration
+	if r.Input != nil {
+		wall += r.Input.StageTime
+	}
+	key := pairKey{r.Task, r.Config.Name}
+	w[key] = append(w[key], wall)
+}
+
+// estimate returns the mean wall time of earlier runs of the same pair,
+// falling back to the same task under any config
+func (w wallTimes) estimate(task, config string) (time.Duration, bool) {
+	if walls := w[pairKey{task, config}]; len(walls) > 0 {
+		return meanDuration(walls), true
+	}
+	var byTask []time.Duration
+	for key, walls := range w {
+		if key.task == task {
+			byTask = append(byTask, walls...)
+		}
+	}
+	if len(byTask) > 0 {
+		return meanDuration(byTask), true
+	}
+	return 0, false
+}
+
+// loadHistory reads the results of earlier sweeps from a comma-separated
+// list of results files and journals (*.jsonl)
+func loadHistory(paths string) ([]runner.BenchmarkResult, error) {
+	var history []runner.BenchmarkResult
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		if strings.HasSuffix(path, ".jsonl") {
+			recorded, err := readJournal(path)
+			if err != nil {
+				return nil, err
+			}
+			history = append(history, recorded...)
+			continue
+		}
+		doc, err := results.Read(path)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, doc.Results...)
+	}
+	return history, nil
+}
+
+// taskPeak is what earlier runs tell about a task's memory needs
+type taskPeak struct {
+	liveHeap uint64 // Largest live heap of any gctrace cycle, 0 = unknown
+	rss      uint64 // Smallest peak RSS of any successful run, 0 = unknown
+}
+
+// peaksOf collects the memory peaks of every task in history, including
+// the tenants of co-tenancy groups under their own task names. The live
+// heap hardly depends on the flags, so its maximum is taken; peak RSS
+// grows with GOGC, so the smallest one is what the task needs at least.
+func peaksOf(history []runner.BenchmarkResult) map[string]taskPeak {
+	peaks := map[string]taskPeak{}
+	var visit func(r runner.BenchmarkResult)
+	visit = func(r runner.BenchmarkResult) {
+		for _, tenant := range r.Tenants {
+			visit(tenant)
+		}
+		if r.Error != "" || len(r.Tenants) > 0 {
+			return
+		}
+		peak := peaks[r.Task]
+		for _, cycle := range r.GCTrace {
+			peak.liveHeap = max(peak.liveHeap, cycle.HeapLive)
+		}
+		if rss := r.Resources.PeakRSS; rss > 0 && (peak.rss == 0 || rss < peak.rss) {
+			peak.rss = rss
+		}
+		peaks[r.Task] = peak
+	}
+	for _, r := range history {
+		visit(r)
+	}
+	return peaks
+}
+
+// configWarnings lists the configs that are likely invalid on this host
+// or for the tasks they will run, judging by the peaks seen in history
+func configWarnings(tasks []runner.AgentTask, configs []runner.BenchmarkConfig, numCPU int, peaks map[string]taskPeak) []string {
+	const mib = 1024 * 1024
+	var warnings []string
+	warn := func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	for _, cfg := range configs {
+		if cfg.MaxProcs > numCPU {
+			warn("%s: GOMAXPROCS=%d exceeds the %d CPUs of this host", cfg.Name, cfg.MaxProcs, numCPU)
+		}
+		if cfg.CPUs > 0 && float64(cfg.MaxProcs) > math.Ceil(cfg.CPUs) {
+			warn("%s: GOMAXPROCS=%d exceeds cpus=%g, so the process is throttled", cfg.Name, cfg.MaxProcs, cfg.CPUs)
+		}
+		if cfg.GCPercent < 0 && cfg.MemLimit == 0 && cfg.MemoryMax == 0 {
+			warn("%s: GOGC=off without GOMEMLIMIT or memory_max_mb lets the heap grow until the run ends", cfg.Name)
+		}
+		if cfg.MemLimit > 0 && cfg.MemoryMax > 0 && cfg.MemLimit >= cfg.MemoryMax {
+			warn("%s: GOMEMLIMIT=%dMiB is not below memory_max_mb=%d, so the cgroup OOM-kills before the limit makes the GC work harder",
+				cfg.Name, cfg.MemLimit, cfg.MemoryMax)
+		}
+
+		for _, task := range tasks {
+			// Limits apply to every process of a group on its own
+			members := []runner.AgentTask{task}
+			if len(task.Tenants) > 0 {
+				members = task.Tenants
+			}
+			seen := map[string]bool{}
+			for _, member := range members {
+				peak, ok := peaks[member.Name]
+				if !ok || seen[member.Name] {
+					continue
+				}
+				seen[member.Name] = true
+				switch {
+				case cfg.MemLimit > 0 && peak.liveHeap > uint64(cfg.MemLimit)*mib:
+					warn("%s/%s: GOMEMLIMIT=%dMiB is below %s's peak live heap of %.0f MiB, so the GC will run almost continuously",
+						task.Name, cfg.Name, cfg.MemLimit, member.Name, float64(peak.liveHeap)/mib)
+				case cfg.MemLimit > 0 && peak.liveHeap == 0 && peak.rss > uint64(cfg.MemLimit)*mib:
+					warn("%s/%s: GOMEMLIMIT=%dMiB is below %s's peak RSS of %.0f MiB (no gctrace history for its live heap)",
+						task.Name, cfg.Name, cfg.MemLimit, member.Name, float64(peak.rss)/mib)
+				}
+				if cfg.MemoryMax > 0 && peak.rss > uint64(cfg.MemoryMax)*mib {
+					warn("%s/%s: memory_max_mb=%d is below %s's peak RSS of %.0f MiB, expect OOM kills",
+						task.Name, cfg.Name, cfg.MemoryMax, member.Name, float64(peak.rss)/mib)
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// printDryRun lists every scheduled run with the command line of its
+// processes, then the estimated wall time of the sweep
+func printDryRun(w io.Writer, bench *runner.Runner, runs []runner.ScheduledRun, done map[runKey]runner.BenchmarkResult, history wallTimes, haveHistory bool) {
+	var estimate, bound time.Duration
+	estimated, unbounded, skipped, warmupsSkipped := 0, 0, 0, 0
+	completed := map[pairKey]int{}
+	for _, r := range done {
+		completed[pairKey{r.Task, r.Config.Name}]++
+	}
+	for seq, run := range runs {
+		rep := fmt.Sprintf("rep %d/%d", run.Rep, run.Task.Count)
+		if run.Warmup {
+			rep = fmt.Sprintf("warmup %d", run.Rep)
+		}
+		fmt.Fprintf(w, "[%d/%d] %s / %s, %s", seq+1, len(runs), run.Task.Name, run.Config.Name, rep)
+		// As in the sweep, warmups are only needed for pairs that still
+		// have runs to do
+		if run.Warmup && completed[pairKey{run.Task.Name, run.Config.Name}] >= run.Task.Count {
+			fmt.Fprintf(w, ": pair complete\n")
+			warmupsSkipped++
+			continue
+		}
+		if _, ok := done[runKey{run.Task.Name, run.Config.Name, run.Rep}]; ok && !run.Warmup {
+			fmt.Fprintf(w, ": already in journal\n")
+			skipped++
+			continue
+		}
+		if wall, ok := history.estimate(run.Task.Name, run.Config.Name); ok {
+			fmt.Fprintf(w, ", ~%v", roundWall(wall))
+			estimate += wall
+			estimated++
+		}
+		fmt.Fprintln(w)
+
+		invocations := bench.Invocations(run.Task, run.Config)
+		longest := time.Duration(0)
+		for _, inv := range invocations {
+			if inv.Timeout == 0 {
+				longest = -1
+			} else if longest >= 0 {
+				longest = max(longest, inv.Timeout)
+			}
+			if len(run.Task.Tenants) > 0 {
+				fmt.Fprintf(w, "    %s: %s\n", inv.Task, commandLine(inv))
+			} else {
+				fmt.Fprintf(w, "    %s\n", commandLine(inv))
+			}
+		}
+		if longest < 0 {
+			unbounded++
+		} else {
+			bound += longest
+		}
+	}
+
+	pending := len(runs) - skipped - warmupsSkipped
+	fmt.Fprintf(w, "\n%s stands for a temporary metrics file, %s for a private copy of the task's input and %s for an empty output directory, all created per run.\n",
+		runner.MetricsPlaceholder, runner.InputPlaceholder, runner.OutputPlaceholder)
+	fmt.Fprintf(w, "Runs to do: %d", pending)
+	switch {
+	case skipped > 0 && warmupsSkipped > 0:
+		fmt.Fprintf(w, " (%d more already in the journal, %d warmup(s) of complete pairs skipped)", skipped, warmupsSkipped)
+	case skipped > 0:
+		fmt.Fprintf(w, " (%d more already in the journal)", skipped)
+	case warmupsSkipped > 0:
+		fmt.Fprintf(w, " (%d warmup(s) of complete pairs skipped)", warmupsSkipped)
+	}
+	fmt.Fprintln(w)
+	switch {
+	case !haveHistory:
+		fmt.Fprintln(w, "Estimated wall time: unknown, pass -history with earlier results to estimate it")
+	case estimated == 0:
+		fmt.Fprintln(w, "Estimated wall time: unknown, the history has no runs of these tasks")
+	case estimated < pending:
+		fmt.Fprintf(w, "Estimated wall time: at least %v (%d of %d runs have no history for their task)\n",
+			roundWall(estimate), pending-estimated, pending)
+	default:
+		fmt.Fprintf(w, "Estimated wall time: %v\n", roundWall(estimate))
+	}
+	if unbounded == 0 {
+		fmt.Fprintf(w, "At most %v if every run hits its timeout\n", roundWall(bound))
+	}
+}
+
+// roundWall rounds a wall time to a precision that suits its size
+func roundWall(d time.Duration) time.Duration {
+	if d < time.Minute {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Second)
+}
+
+// commandLine formats an invocation as a shell command
+func commandLine(inv runner.Invocation) string {
+	parts := []string{}
+	if inv.Dir != "" {
+		parts = append(parts, "cd", shellQuote(inv.Dir), "&&")
+	}
+	for _, kv := range inv.Env {
+		parts = append(parts, shellQuote(kv))
+	}
+	parts = append(parts, shellQuote(inv.Command))
+	for _, arg := range inv.Args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./{}-]+$`)
+
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
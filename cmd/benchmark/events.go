@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/gctrace"
+	"github.com/natalie/go-flags-eval/internal/runner"
+)
+
+// EventType names the events of a sweep
+type EventType string
+
+const (
+	EventSweepStarted  EventType = "sweep-started"  // Total and Schedule
+	EventRunStarted    EventType = "run-started"    // A run or warmup was started
+	EventSample        EventType = "sample"         // A /proc sample of the running agent (-proc-interval)
+	EventRunFinished   EventType = "run-finished"   // Result holds every metric of the run
+	EventRunSkipped    EventType = "run-skipped"    // Reason says why; journaled runs carry their Result
+	EventPairFinished  EventType = "pair-finished"  // All repetitions of a task/config pair are in; Summary
+	EventSweepFinished EventType = "sweep-finished" // Interrupted is set if the sweep was stopped early
+)
+
+// Event is one line of the -events=jsonl stream. Seq, Task, Config, Rep
+// and Warmup identify the run an event belongs to.
+type Event struct {
+	Type        EventType                `json:"type"`
+	Time        time.Time                `json:"time"`
+	Seq         int                      `json:"seq,omitempty"`   // Position in the run order, from 1
+	Total       int                      `json:"total,omitempty"` // Runs in the sweep, warmups included
+	Task        string                   `json:"task,omitempty"`
+	Description string                   `json:"description,omitempty"`
+	Config      string                   `json:"config,omitempty"`
+	Rep         int                      `json:"rep,omitempty"`
+	Count       int                      `json:"count,omitempty"` // Repetitions (or warmups) of the pair
+	Warmup      bool                     `json:"warmup,omitempty"`
+	Reason      string                   `json:"reason,omitempty"`
+	Interrupted bool                     `json:"interrupted,omitempty"`
+	Schedule    *runner.Schedule         `json:"schedule,omitempty"`
+	Sample      *runner.ProcSample       `json:"sample,omitempty"`
+	Result      *runner.BenchmarkResult  `json:"result,omitempty"`
+	Summary     *runner.BenchmarkSummary `json:"summary,omitempty"`
+}
+
+// reporter presents the events of a sweep. Emit may be called from the
+// sampling goroutine while a run is in flight.
+type reporter interface {
+	Emit(e Event)
+	Close()
+}
+
+// newReporter returns the reporter for -events, which main has validated
+func newReporter(mode string, w io.Writer, runs []runner.ScheduledRun, gcTrace bool) reporter {
+	switch mode {
+	case "jsonl":
+		return &jsonlReporter{enc: json.NewEncoder(w)}
+	case "live":
+		return newLiveReporter(w, runs)
+	}
+	return &textReporter{w: w, gcTrace: gcTrace}
+}
+
+// jsonlReporter writes every event as a JSON line
+type jsonlReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (r *jsonlReporter) Emit(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(e)
+}
+
+func (r *jsonlReporter) Close() {}
+
+// textReporter prints one line per run, grouped by task
+type textReporter struct {
+	w           io.Writer
+	gcTrace     bool
+	currentTask string
+}
+
+func (r *textReporter) Emit(e Event) {
+	switch e.Type {
+	case EventRunStarted:
+		r.runHeader(e)
+
+	case EventRunSkipped:
+		if e.Warmup {
+			return
+		}
+		r.runHeader(e)
+		fmt.Fprintln(r.w, e.Reason)
+
+	case EventRunFinished:
+		result := e.Result
+		switch {
+		case e.Interrupted:
+			fmt.Fprintln(r.w, "interrupted")
+		case result.Failure != nil:
+			fmt.Fprintf(r.w, "FAILED (%s): %s\n", result.Failure.Class, result.Error)
+		case e.Warmup:
+			fmt.Fprintf(r.w, "Duration: %v (discarded)\n", result.Duration)
+		default:
+			fmt.Fprintf(r.w, "Duration: %v, Memory: %.2f MB, Peak RSS: %.2f MB, GC runs: %d\n",
+				result.Duration,
+				float64(result.MemoryAllocated)/(1024*1024),
+				float64(result.Resources.PeakRSS)/(1024*1024),
+				result.NumGC)
+			if r.gcTrace {
+				trace := gctrace.Summarize(result.GCTrace)
+				fmt.Fprintf(r.w, "  gctrace: %d cycles (%d forced, %d limit-triggered), STW total %v, GC CPU %.0f%%\n",
+					trace.Cycles, trace.Forced, trace.LimitTriggered, trace.TotalSTW, trace.FinalCPU)
+			}
+			if ss := result.SteadyState; ss != nil {
+				fmt.Fprintf(r.w, "  steady state: %.2f ops/sec over %v, p50 %v, p90 %v, p99 %v, p999 %v\n",
+					ss.OpsPerSec, ss.Window.Round(time.Millisecond),
+					ss.LatencyP50.Round(time.Microsecond), ss.LatencyP90.Round(time.Microsecond),
+					ss.LatencyP99.Round(time.Microsecond), ss.LatencyP999.Round(time.Microsecond))
+			}
+			if co := result.CoTenancy; co != nil {
+				fmt.Fprintf(r.w, "  co-tenancy: %d processes, %.2f/s, p50 %v, p99 %v, max %v, start skew %v\n",
+					co.Processes, co.Throughput,
+					co.DurationP50.Round(time.Millisecond), co.DurationP99.Round(time.Millisecond),
+					co.DurationMax.Round(time.Millisecond), co.StartSkew.Round(time.Microsecond))
+			}
+			for _, b := range result.Benchmarks {
+				fmt.Fprintf(r.w, "  %s: %.2f ns/op, %.0f B/op, %.0f allocs/op\n", b.Name, b.NsPerOp, b.BytesPerOp, b.AllocsPerOp)
+			}
+		}
+
+	case EventPairFinished:
+		if e.Count > 1 {
+			fmt.Fprintf(r.w, "  => %s: %s\n", e.Config, e.Summary)
+		}
+	}
+}
+
+// runHeader starts the line of a run, after the task's header if the run
+// starts a new task
+func (r *textReporter) runHeader(e Event) {
+	if e.Task != r.currentTask {
+		r.currentTask = e.Task
+		fmt.Fprintf(r.w, "\n=== Running Task: %s ===\n", e.Task)
+		fmt.Fprintf(r.w, "Description: %s\n\n", e.Description)
+	}
+	switch {
+	case e.Warmup:
+		fmt.Fprintf(r.w, "Warming up configuration: %s [%d/%d]... ", e.Config, e.Rep, e.Count)
+	case e.Count > 1:
+		fmt.Fprintf(r.w, "Testing configuration: %s [%d/%d]... ", e.Config, e.Rep, e.Count)
+	default:
+		fmt.Fprintf(r.w, "Testing configuration: %s... ", e.Config)
+	}
+}
+
+func (r *textReporter) Close() {}
+
+// liveReporter redraws a compact view in place: a progress bar with an
+// ETA, the run in flight and a rolling table of the latest results
+type liveReporter struct {
+	w    io.Writer
+	runs []runner.ScheduledRun
+	stop chan struct{}
+	done chan struct{}
+
+	mu        sync.Mutex
+	start     time.Time
+	completed int       // Runs finished or skipped
+	current   *Event    // The run in flight
+	runStart  time.Time // Of the run in flight
+	sample    *runner.ProcSample
+	walls     wallTimes // Observed wall time per pair, for the ETA
+	recent    []Event   // Latest finished runs, oldest first
+	lines     int       // Lines drawn by the last redraw
+}
+
+// liveRecent is the number of finished runs kept in the table
+const liveRecent = 8
+
+func newLiveReporter(w io.Writer, runs []runner.ScheduledRun) *liveReporter {
+	r := &liveReporter{
+		w:     w,
+		runs:  runs,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+		start: time.Now(),
+		walls: wallTimes{},
+	}
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.mu.Lock()
+				r.redraw()
+				r.mu.Unlock()
+			}
+		}
+	}()
+	return r
+}
+
+func (r *liveReporter) Emit(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := pairKey{e.Task, e.Config}
+	switch e.Type {
+	case EventRunStarted:
+		r.current, r.runStart, r.sample = &e, e.Time, nil
+	case EventSample:
+		r.sample = e.Sample
+	case EventRunSkipped:
+		r.completed++
+		// Runs recorded by an earlier invocation still tell how long
+		// the pair takes
+		if e.Result != nil {
+			r.walls.addResult(*e.Result)
+		}
+	case EventRunFinished:
+		r.completed++
+		r.current = nil
+		if e.Interrupted {
+			break
+		}
+		r.walls[key] = append(r.walls[key], e.Time.Sub(r.runStart))
+		if !e.Warmup {
+			r.recent = append(r.recent, e)
+			if len(r.recent) > liveRecent {
+				r.recent = r.recent[1:]
+			}
+		}
+	default:
+		return
+	}
+	r.redraw()
+}
+
+func (r *liveReporter) Close() {
+	close(r.stop)
+	<-r.done
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redraw()
+}
+
+// redraw replaces the previous view; r.mu must be held
+func (r *liveReporter) redraw() {
+	var buf bytes.Buffer
+	total := len(r.runs)
+	elapsed := time.Since(r.start)
+
+	const width = 30
+	filled := 0
+	if total > 0 {
+		filled = width * r.completed / total
+	}
+	fmt.Fprintf(&buf, "[%s%s] %d/%d runs, elapsed %v",
+		strings.Repeat("#", filled), strings.Repeat("-", width-filled),
+		r.completed, total, elapsed.Round(time.Second))
+	if eta, ok := r.eta(); ok && r.completed < total {
+		fmt.Fprintf(&buf, ", ETA %v", eta.Round(time.Second))
+	}
+	buf.WriteString("\n")
+
+	if c := r.current; c != nil {
+		what := "Running"
+		if c.Warmup {
+			what = "Warming up"
+		}
+		fmt.Fprintf(&buf, "%s %s / %s [%d/%d]  %v", what, c.Task, c.Config, c.Rep, c.Count, time.Since(r.runStart).Round(100*time.Millisecond))
+		if s := r.sample; s != nil {
+			fmt.Fprintf(&buf, "  RSS %.1f MB, %d threads", float64(s.RSS)/(1024*1024), s.Threads)
+		}
+		buf.WriteString("\n")
+	} else {
+		buf.WriteString("\n")
+	}
+
+	if len(r.recent) > 0 {
+		buf.WriteString("\n")
+		tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TASK\tCONFIG\tREP\tDURATION\tMEMORY\tPEAK RSS\tGC")
+		for _, e := range r.recent {
+			res := e.Result
+			if res.Failure != nil {
+				fmt.Fprintf(tw, "%s\t%s\t%d\tFAILED (%s)\t\t\t\n", e.Task, e.Config, e.Rep, res.Failure.Class)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%v\t%.2f MB\t%.2f MB\t%d\n",
+				e.Task, e.Config, e.Rep,
+				res.Duration.Round(time.Millisecond),
+				float64(res.MemoryAllocated)/(1024*1024),
+				float64(res.Resources.PeakRSS)/(1024*1024),
+				res.NumGC)
+		}
+		tw.Flush()
+	}
+
+	// Move to the start of the previous view and clear it
+	if r.lines > 0 {
+		fmt.Fprintf(r.w, "\x1b[%dF\x1b[J", r.lines)
+	}
+	r.w.Write(buf.Bytes())
+	r.lines = bytes.Count(buf.Bytes(), []byte("\n"))
+}
+
+// eta estimates the time left from the wall time of earlier runs of the
+// same pair, falling back to the same task and then to all runs
+func (r *liveReporter) eta() (time.Duration, bool) {
+	var all []time.Duration
+	for _, walls := range r.walls {
+		all = append(all, walls...)
+	}
+	if len(all) == 0 {
+		return 0, false
+	}
+
+	estimate := func(run runner.ScheduledRun) time.Duration {
+		if wall, ok := r.walls.estimate(run.Task.Name, run.Config.Name); ok {
+			return wall
+		}
+		return meanDuration(all)
+	}
+
+	left := time.Duration(0)
+	next := r.completed
+	if c := r.current; c != nil {
+		next = c.Seq
+		left += max(0, estimate(r.runs[c.Seq-1])-time.Since(r.runStart))
+	}
+	for _, run := range r.runs[min(next, len(r.runs)):] {
+		left += estimate(run)
+	}
+	return left, true
+}
+
+func meanDuration(values []time.Duration) time.Duration {
+	sum := time.Duration(0)
+	for _, v := range values {
+		sum += v
+	}
+	return sum / time.Duration(len(values))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/natalie/go-flags-eval/internal/runner"
+)
+
+// journal appends every completed run to a JSONL file as soon as it
+// finishes, so an interrupted sweep loses at most the run in flight
+type journal struct {
+	f   *os.File
+	enc *json.Encoder
+}
+
+// runKey identifies one repetition of a task/config pair
+type runKey struct {
+	task       string
+	config     string
+	repetition int
+}
+
+func keyOf(r runner.BenchmarkResult) runKey {
+	return runKey{r.Task, r.Config.Name, r.Repetition}
+}
+
+// journalPath derives the default journal location from the output file
+func journalPath(output string) string {
+	return strings.TrimSuffix(output, ".json") + ".journal.jsonl"
+}
+
+// logsPath derives the default agent log directory from the output file
+func logsPath(output string) string {
+	return strings.TrimSuffix(output, ".json") + ".logs"
+}
+
+// clearLogs removes the run logs of an earlier sweep from dir
+func clearLogs(dir string) error {
+	logs, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		return err
+	}
+	for _, path := range logs {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openJournal opens the journal at path. With resume it returns the runs
+// already recorded and appends after them; otherwise it starts afresh.
+func openJournal(path string, resume bool) (*journal, []runner.BenchmarkResult, error) {
+	var previous []runner.BenchmarkResult
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		var end int64
+		var err error
+		previous, end, err = scanJournal(path)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return nil, nil, err
+		default:
+			// Cut off a damaged final line, or the next record would be
+			// appended to it and make the journal unreadable
+			if err := os.Truncate(path, end); err != nil {
+				return nil, nil, err
+			}
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &journal{f: f, enc: json.NewEncoder(f)}, previous, nil
+}
+
+// Append writes one result and syncs it to disk
+func (j *journal) Append(r runner.BenchmarkResult) error {
+	if err := j.enc.Encode(r); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+func (j *journal) Close() error {
+	return j.f.Close()
+}
+
+// readJournal reads every complete record from a journal. A truncated
+// final line, as left by a crash mid-write, is ignored.
+func readJournal(path string) ([]runner.BenchmarkResult, error) {
+	results, _, err := scanJournal(path)
+	return results, err
+}
+
+// scanJournal is readJournal that also returns the offset just past the
+// last complete record. Only newline-terminated lines are complete, as
+// Append writes every record with its newline.
+func scanJournal(path string) ([]runner.BenchmarkResult, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	results := []runner.BenchmarkResult{}
+	reader := bufio.NewReader(f)
+	line := 0
+	offset, end := int64(0), int64(0)
+	var pending error
+	for {
+		data, err := reader.ReadBytes('\n')
+		if len(data) > 0 {
+			line++
+			if pending != nil {
+				// Only the last line may be damaged
+				return nil, 0, pending
+			}
+			offset += int64(len(data))
+			if data[len(data)-1] != '\n' {
+				pending = fmt.Errorf("%s:%d: unterminated record", path, line)
+			} else if len(bytes.TrimSpace(data)) == 0 {
+				end = offset
+			} else {
+				var r runner.BenchmarkResult
+				if err := json.Unmarshal(data, &r); err != nil {
+					pending = fmt.Errorf("%s:%d: %w", path, line, err)
+				} else {
+					results = append(results, r)
+					end = offset
+				}
+			}
+		}
+		if err == io.EOF {
+			return results, end, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/natalie/go-flags-eval/internal/runner"
+)
+
+func result(task string, rep int) runner.BenchmarkResult {
+	return runner.BenchmarkResult{Task: task, Config: runner.BenchmarkConfig{Name: "default"}, Repetition: rep}
+}
+
+func appendAll(t *testing.T, path string, resume bool, results ...runner.BenchmarkResult) []runner.BenchmarkResult {
+	t.Helper()
+	j, previous, err := openJournal(path, resume)
+	if err != nil {
+		t.Fatalf("openJournal: %v", err)
+	}
+	for _, r := range results {
+		if err := j.Append(r); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return previous
+}
+
+func keys(results []runner.BenchmarkResult) []runKey {
+	ks := []runKey{}
+	for _, r := range results {
+		ks = append(ks, keyOf(r))
+	}
+	return ks
+}
+
+func TestJournalResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.journal.jsonl")
+
+	if previous := appendAll(t, path, true, result("a", 1), result("a", 2)); len(previous) != 0 {
+		t.Fatalf("resuming a missing journal returned %d runs", len(previous))
+	}
+	previous := appendAll(t, path, true, result("a", 3))
+	if len(previous) != 2 {
+		t.Fatalf("resume returned %v, want 2 runs", keys(previous))
+	}
+
+	// Without resume the journal starts afresh
+	if previous := appendAll(t, path, false, result("b", 1)); len(previous) != 0 {
+		t.Fatalf("fresh journal returned %d runs", len(previous))
+	}
+	got, err := readJournal(path)
+	if err != nil || len(got) != 1 || got[0].Task != "b" {
+		t.Fatalf("readJournal = %v, %v, want only b", keys(got), err)
+	}
+}
+
+// A crash mid-write leaves a partial last line. Resuming must drop it, so
+// that later records do not run into it and the journal stays readable
+// across further resumes.
+func TestJournalResumeAfterCrash(t *testing.T) {
+	for _, tail := range []string{
+		`{"Task":"a","Config":{"Na`,
+		`{"Task":"a","Config":{"Name":"default"},"Repetition":3}`, // Valid, but without its newline
+		"\x00\x00\x00",
+	} {
+		path := filepath.Join(t.TempDir(), "results.journal.jsonl")
+		appendAll(t, path, false, result("a", 1), result("a", 2))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(tail)
+		f.Close()
+
+		previous := appendAll(t, path, true, result("a", 3))
+		if len(previous) != 2 {
+			t.Errorf("tail %q: first resume returned %v, want 2 runs", tail, keys(previous))
+		}
+		previous = appendAll(t, path, true, result("a", 4))
+		if len(previous) != 3 {
+			t.Errorf("tail %q: second resume returned %v, want 3 runs", tail, keys(previous))
+		}
+		got, err := readJournal(path)
+		if err != nil || len(got) != 4 {
+			t.Errorf("tail %q: readJournal = %v, %v, want 4 runs", tail, keys(got), err)
+		}
+	}
+}
+
+func TestReadJournalDamagedMiddle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.journal.jsonl")
+	data := `{"Task":"a","Repetition":1}` + "\n" +
+		`{"Task":"a","Rep` + "\n" +
+		`{"Task":"a","Repetition":3}` + "\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := readJournal(path)
+	if err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("readJournal error = %v, want one for line 2", err)
+	}
+	if _, _, err := openJournal(path, true); err == nil {
+		t.Errorf("openJournal resumed a journal damaged in the middle")
+	}
+}
+
+func TestReadJournalBlankLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.journal.jsonl")
+	data := "\n" + `{"Task":"a","Repetition":1}` + "\n  \n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, end, err := scanJournal(path)
+	if err != nil || len(got) != 1 || end != int64(len(data)) {
+		t.Errorf("scanJournal = %v, %d, %v, want 1 run ending at %d", keys(got), end, err, len(data))
+	}
+}
@@ -2,104 +2,151 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
-	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"sort"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/natalie/go-flags-eval/internal/agentmetrics"
+	"github.com/natalie/go-flags-eval/internal/results"
+	"github.com/natalie/go-flags-eval/internal/runner"
 )
 
-// BenchmarkConfig defines a set of Go runtime flags to test
-type BenchmarkConfig struct {
-	Name      string
-	MaxProcs  int
-	MemLimit  int64 // in MB
-	GCPercent int
-}
-
-// BenchmarkResult stores the results of a benchmark run
-type BenchmarkResult struct {
-	Config           BenchmarkConfig
-	Duration         time.Duration
-	MemoryAllocated  uint64
-	NumGC            uint32
-	PauseTimeNs      uint64
-	ExitCode         int
-	Error            string
-}
-
-// AgentTask represents a task for an agent to perform
-type AgentTask struct {
-	Name        string
-	Command     string
-	Args        []string
-	Description string
-}
+// pairKey identifies a task/config pair
+type pairKey struct{ task, config string }
 
 var (
-	outputFile = flag.String("output", "benchmark_results.json", "Output file for benchmark results")
-	taskName   = flag.String("task", "all", "Specific task to run (all, code-gen, file-search, code-analysis)")
+	outputFile     = flag.String("output", "benchmark_results.json", "Output file for benchmark results")
+	taskName       = flag.String("task", "all", "Specific task to run (all, code-gen, file-search, code-analysis)")
+	count          = flag.Int("count", 1, "Number of times to run each task/config pair (overrides the plan)")
+	planFile       = flag.String("plan", "", "JSON plan file describing configs and tasks (default: built-in sweep)")
+	sweepExpr      = flag.String("sweep", "", "Sweep expression replacing the configs, e.g. \"GOMAXPROCS=1,2,4 GOGC=50..200:step50 GOMEMLIMIT=256MiB,off\"")
+	constrain      = flag.String("constraints", "", "Semicolon-separated constraints pruning -sweep, e.g. \"GOGC=off => GOMEMLIMIT!=off\"")
+	defaultTimeout = flag.Duration("timeout", 10*time.Minute, "Per-run timeout for tasks and configs that do not set their own (0 = none)")
+	cgroupRoot     = flag.String("cgroup-root", "", "Delegated cgroup v2 directory; each run is placed in a transient leaf below it with the config's limits (Linux)")
+	gcTrace        = flag.Bool("gctrace", false, "Run agents with GODEBUG=gctrace=1 and store the parsed GC cycles per run")
+	procInterval   = flag.Duration("proc-interval", 50*time.Millisecond, "How often to sample /proc/<pid> of the running agent (Linux)")
+	buildDir       = flag.String("build-dir", filepath.Join(os.TempDir(), "go-flags-eval-agents"), "Directory where agent binaries are built and cached")
+	stageDir       = flag.String("stage-dir", "", "Directory where task inputs are copied for each run; on the inputs' filesystem, reflinks or hardlinks avoid full copies (default: system temp directory)")
+	journalFile    = flag.String("journal", "", "JSONL file each run is appended to as it completes (default: <output>.journal.jsonl)")
+	runOrder       = flag.String("order", string(runner.OrderSequential), "Run order within a task: sequential, round-robin or shuffle")
+	seed           = flag.Int64("seed", 0, "Seed for -order=shuffle (0 = random, the seed used is recorded)")
+	warmup         = flag.Int("warmup", 1, "Discarded warmup runs per task/config pair")
+	resume         = flag.Bool("resume", false, "Keep the existing journal and skip the runs already recorded in it")
+	eventsMode     = flag.String("events", "text", "Progress output: text (a line per run), jsonl (an event stream on stdout, messages on stderr) or live (a redrawn progress view)")
+	tenants        = flag.Int("tenants", 1, "Run each task as a co-tenancy group of this many concurrent processes")
+	logDir         = flag.String("log-dir", "", "Directory for the stdout/stderr log file of each run (default: <output>.logs)")
+	dryRun         = flag.Bool("dry-run", false, "Print every run with the exact command line and environment of its processes, and the estimated wall time, without running anything")
+	historyFiles   = flag.String("history", "", "Comma-separated results files or journals of earlier sweeps, for -dry-run estimates and warnings about configs below a task's known memory peaks")
 )
 
+// console receives the runner's own messages; with -events=jsonl it is
+// stderr, leaving stdout to the event stream
+var console io.Writer = os.Stdout
+
 func main() {
 	flag.Parse()
 
-	ctx := context.Background()
-
-	// Define benchmark configurations
-	configs := []BenchmarkConfig{
-		{"default", 0, 0, 100},
-		{"maxprocs-1", 1, 0, 100},
-		{"maxprocs-2", 2, 0, 100},
-		{"maxprocs-4", 4, 0, 100},
-		{"maxprocs-8", 8, 0, 100},
-		{"memlimit-256", 0, 256, 100},
-		{"memlimit-512", 0, 512, 100},
-		{"memlimit-1024", 0, 1024, 100},
-		{"gc-50", 0, 0, 50},
-		{"gc-200", 0, 0, 200},
-		{"gc-off", 0, 0, -1},
-		{"constrained", 2, 256, 50},
-		{"performance", 8, 2048, 200},
-	}
-
-	// Define agent tasks with heavier workloads
-	tasks := []AgentTask{
-		{
-			Name:        "code-gen",
-			Command:     "go",
-			Args:        []string{"run", "./cmd/agents/code_generator", "-files=100", "-lines=500"},
-			Description: "Generate 100 Go files with 500 lines each (heavy workload)",
-		},
-		{
-			Name:        "file-search",
-			Command:     "go",
-			Args:        []string{"run", "./cmd/agents/file_searcher", "-pattern=func", "-dir=./testdata", "-workers=8"},
-			Description: "Search for 'func' pattern across ~300 files",
-		},
-		{
-			Name:        "refactor",
-			Command:     "go",
-			Args:        []string{"run", "./cmd/agents/refactor", "-target=./testdata", "-operation=rename"},
-			Description: "Rename variables across ~300 files",
-		},
-		{
-			Name:        "ast-parser",
-			Command:     "go",
-			Args:        []string{"run", "./cmd/agents/ast_parser", "-target=./testdata"},
-			Description: "Parse ~300 Go files and extract AST information (memory-intensive)",
-		},
+	if *count < 1 {
+		log.Fatalf("Invalid -count=%d: must be at least 1", *count)
+	}
+	if *warmup < 0 {
+		log.Fatalf("Invalid -warmup=%d: must not be negative", *warmup)
+	}
+	if *tenants < 1 {
+		log.Fatalf("Invalid -tenants=%d: must be at least 1", *tenants)
+	}
+	switch *eventsMode {
+	case "text", "live":
+	case "jsonl":
+		console = os.Stderr
+	default:
+		log.Fatalf("Unknown -events=%s (want text, jsonl or live)", *eventsMode)
+	}
+
+	// The first SIGINT/SIGTERM stops the sweep after saving partial
+	// results; a second one kills the runner outright
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// Load the sweep from a plan file, or fall back to the built-in one
+	configs := runner.DefaultConfigs()
+	tasks := runner.DefaultTasks()
+	defaultCount := *count
+	schedule := runner.Schedule{Order: runner.RunOrder(*runOrder), Seed: *seed, Warmup: *warmup}
+	if *planFile != "" {
+		plan, err := runner.LoadPlan(*planFile)
+		if err != nil {
+			log.Fatalf("Failed to load plan: %v", err)
+		}
+		configs, err = plan.BenchmarkConfigs()
+		if err != nil {
+			log.Fatalf("Failed to expand plan: %v", err)
+		}
+		tasks = plan.AgentTasks()
+		if plan.Count > 0 && !isFlagSet("count") {
+			defaultCount = plan.Count
+		}
+		if plan.Order != "" && !isFlagSet("order") {
+			schedule.Order = runner.RunOrder(plan.Order)
+		}
+		if plan.Seed != 0 && !isFlagSet("seed") {
+			schedule.Seed = plan.Seed
+		}
+		if plan.Warmup != nil && !isFlagSet("warmup") {
+			schedule.Warmup = *plan.Warmup
+		}
+	}
+	if *sweepExpr != "" {
+		sweep, err := runner.ParseSweep(*sweepExpr, strings.Split(*constrain, ";"))
+		if err != nil {
+			log.Fatalf("Invalid sweep: %v", err)
+		}
+		configs = sweep.Expand()
+		if len(configs) == 0 {
+			log.Fatalf("Sweep is empty: all %d points are excluded by the constraints", sweep.Size())
+		}
+		fmt.Fprintf(console, "Sweep expanded to %d of %d configurations\n", len(configs), sweep.Size())
+	} else if *constrain != "" {
+		log.Fatalf("-constraints requires -sweep")
+	}
+	if _, err := runner.ParseRunOrder(string(schedule.Order)); err != nil {
+		log.Fatalf("Invalid -order: %v", err)
+	}
+	if schedule.Order != runner.OrderShuffle {
+		schedule.Seed = 0
+	} else if schedule.Seed == 0 {
+		schedule.Seed = time.Now().UnixNano()
+	}
+	if *cgroupRoot == "" {
+		for _, cfg := range configs {
+			if runner.HasCgroupLimits(cfg) {
+				log.Fatalf("Config %s sets memory_max_mb/memory_high_mb/cpus, which require -cgroup-root", cfg.Name)
+			}
+		}
+	}
+	for i := range tasks {
+		if tasks[i].Count == 0 || isFlagSet("count") {
+			tasks[i].Count = defaultCount
+		}
 	}
 
 	// Filter tasks if specific task requested
 	if *taskName != "all" {
-		filtered := []AgentTask{}
+		filtered := []runner.AgentTask{}
 		for _, task := range tasks {
 			if task.Name == *taskName {
 				filtered = append(filtered, task)
@@ -111,138 +158,349 @@ func main() {
 		tasks = filtered
 	}
 
-	// Run benchmarks
-	results := []BenchmarkResult{}
-	for _, task := range tasks {
-		fmt.Printf("\n=== Running Task: %s ===\n", task.Name)
-		fmt.Printf("Description: %s\n\n", task.Description)
-
-		for _, cfg := range configs {
-			fmt.Printf("Testing configuration: %s... ", cfg.Name)
-			result := runBenchmark(ctx, task, cfg)
-			results = append(results, result)
-
-			if result.Error != "" {
-				fmt.Printf("ERROR: %s\n", result.Error)
-			} else {
-				fmt.Printf("Duration: %v, Memory: %.2f MB, GC runs: %d\n",
-					result.Duration,
-					float64(result.MemoryAllocated)/(1024*1024),
-					result.NumGC)
+	// -tenants turns every task into a group of identical processes
+	if *tenants > 1 {
+		for i, task := range tasks {
+			if len(task.Tenants) > 0 {
+				continue
 			}
+			group := runner.AgentTask{
+				Name:        fmt.Sprintf("%s-x%d", task.Name, *tenants),
+				Description: fmt.Sprintf("%d concurrent %s processes", *tenants, task.Name),
+				Count:       task.Count,
+			}
+			for range *tenants {
+				group.Tenants = append(group.Tenants, task)
+			}
+			tasks[i] = group
 		}
 	}
 
-	// Save results to JSON
-	if err := saveResults(*outputFile, results); err != nil {
-		log.Fatalf("Failed to save results: %v", err)
+	env := runner.CollectEnvironment()
+	fmt.Fprintf(console, "Host: %s (%s/%s, %s, %d CPUs), %s\n", env.Hostname, env.GOOS, env.GOARCH, env.CPUModel, env.NumCPU, env.GoVersion)
+
+	// Point out likely misconfigurations before spending hours on them
+	var history []runner.BenchmarkResult
+	if *historyFiles != "" {
+		var err error
+		history, err = loadHistory(*historyFiles)
+		if err != nil {
+			log.Fatalf("Failed to load history: %v", err)
+		}
+	}
+	for _, warning := range configWarnings(tasks, configs, env.NumCPU, peaksOf(history)) {
+		fmt.Fprintf(console, "Warning: %s\n", warning)
 	}
 
-	fmt.Printf("\n\nResults saved to: %s\n", *outputFile)
-	printSummary(results)
-}
+	if *dryRun {
+		// Nothing is built, journaled or logged; binaries are named by
+		// the cache entry the current sources would build
+		resolve := func(task *runner.AgentTask) {
+			if task.Package == "" {
+				return
+			}
+			binary, err := runner.BinaryPath(*buildDir, *task)
+			if err != nil {
+				log.Fatalf("Failed to resolve %s: %v", task.Name, err)
+			}
+			task.Command = binary
+			if task.Bench != "" {
+				if task.Dir, err = runner.PackageDir(task.Package); err != nil {
+					log.Fatalf("Failed to resolve %s: %v", task.Name, err)
+				}
+			}
+		}
+		for i := range tasks {
+			for j := range tasks[i].Tenants {
+				resolve(&tasks[i].Tenants[j])
+			}
+			resolve(&tasks[i])
+		}
+
+		walls := wallTimes{}
+		for _, r := range history {
+			walls.addResult(r)
+		}
+		done := map[runKey]runner.BenchmarkResult{}
+		if *resume {
+			if *journalFile == "" {
+				*journalFile = journalPath(*outputFile)
+			}
+			previous, err := readJournal(*journalFile)
+			if err != nil && !os.IsNotExist(err) {
+				log.Fatalf("Failed to read journal: %v", err)
+			}
+			for _, r := range previous {
+				done[keyOf(r)] = r
+				walls.addResult(r)
+			}
+		}
 
-func runBenchmark(ctx context.Context, task AgentTask, cfg BenchmarkConfig) BenchmarkResult {
-	result := BenchmarkResult{
-		Config: cfg,
+		fmt.Fprintln(console)
+		bench := &runner.Runner{DefaultTimeout: *defaultTimeout, GCTrace: *gcTrace}
+		printDryRun(console, bench, schedule.Layout(tasks, configs), done, walls, *historyFiles != "" || len(done) > 0)
+		return
 	}
 
-	// Create temporary file for metrics
-	metricsFile, err := os.CreateTemp("", "agent-metrics-*.json")
+	// Build agent binaries once so that measured durations exclude the
+	// compiler and the go command's own overhead
+	builds := []runner.AgentBuild{}
+	built := map[string]runner.AgentBuild{} // By task name, as tenants repeat tasks
+	buildTask := func(task *runner.AgentTask) {
+		if task.Package == "" {
+			return
+		}
+		build, ok := built[task.Name]
+		if !ok {
+			var err error
+			build, err = runner.BuildTask(*buildDir, *task)
+			if err != nil {
+				log.Fatalf("Failed to build %s: %v", task.Name, err)
+			}
+			if build.Cached {
+				fmt.Fprintf(console, "Using cached %s binary: %s\n", task.Name, build.Binary)
+			} else {
+				fmt.Fprintf(console, "Built %s in %v: %s\n", task.Name, build.BuildTime, build.Binary)
+			}
+			built[task.Name] = build
+			builds = append(builds, build)
+		}
+		task.Command, task.Dir = build.Binary, build.Dir
+	}
+	for i := range tasks {
+		for j := range tasks[i].Tenants {
+			buildTask(&tasks[i].Tenants[j])
+		}
+		buildTask(&tasks[i])
+	}
+
+	// Completed runs are journaled one by one, so an interrupted sweep
+	// can be continued with -resume
+	if *journalFile == "" {
+		*journalFile = journalPath(*outputFile)
+	}
+	jrnl, previous, err := openJournal(*journalFile, *resume)
 	if err != nil {
-		result.Error = fmt.Sprintf("Failed to create metrics file: %v", err)
-		return result
+		log.Fatalf("Failed to open journal: %v", err)
+	}
+	defer jrnl.Close()
+	done := map[runKey]runner.BenchmarkResult{}
+	for _, r := range previous {
+		done[keyOf(r)] = r
+	}
+	if *resume {
+		fmt.Fprintf(console, "Resuming from %s: %d runs already completed\n", *journalFile, len(done))
+	}
+
+	// Agent output goes to a log file per run rather than the console;
+	// like the journal, the logs start afresh unless resuming
+	if *logDir == "" {
+		*logDir = logsPath(*outputFile)
+	}
+	if !*resume {
+		if err := clearLogs(*logDir); err != nil {
+			log.Fatalf("Failed to clear logs: %v", err)
+		}
 	}
-	metricsPath := metricsFile.Name()
-	metricsFile.Close()
-	defer os.Remove(metricsPath)
+	fmt.Fprintf(console, "Agent logs: %s\n", *logDir)
 
-	// Prepare environment
-	env := os.Environ()
-	if cfg.MaxProcs > 0 {
-		env = append(env, fmt.Sprintf("GOMAXPROCS=%d", cfg.MaxProcs))
+	// Run benchmarks
+	bench := &runner.Runner{
+		DefaultTimeout: *defaultTimeout,
+		CgroupRoot:     *cgroupRoot,
+		GCTrace:        *gcTrace,
+		ProcInterval:   *procInterval,
+		StageDir:       *stageDir,
+		LogDir:         *logDir,
 	}
-	if cfg.MemLimit > 0 {
-		env = append(env, fmt.Sprintf("GOMEMLIMIT=%dMiB", cfg.MemLimit))
+	if schedule.Order == runner.OrderShuffle {
+		fmt.Fprintf(console, "Run order: shuffle (seed %d)\n", schedule.Seed)
+	} else {
+		fmt.Fprintf(console, "Run order: %s\n", schedule.Order)
 	}
-	if cfg.GCPercent != 100 {
-		env = append(env, fmt.Sprintf("GOGC=%d", cfg.GCPercent))
+	samples := map[pairKey][]runner.BenchmarkResult{}
+	for _, r := range previous {
+		key := pairKey{r.Task, r.Config.Name}
+		samples[key] = append(samples[key], r)
 	}
 
-	// Add metrics output flag to args
-	args := append(task.Args, fmt.Sprintf("-metrics-output=%s", metricsPath))
+	runs := schedule.Layout(tasks, configs)
+	events := newReporter(*eventsMode, os.Stdout, runs, *gcTrace)
+	events.Emit(Event{Type: EventSweepStarted, Time: time.Now(), Total: len(runs), Schedule: &schedule})
 
-	// Run command
-	cmd := exec.CommandContext(ctx, task.Command, args...)
-	cmd.Env = env
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	for seq, run := range runs {
+		if ctx.Err() != nil {
+			break
+		}
+		task, cfg, key := run.Task, run.Config, pairKey{run.Task.Name, run.Config.Name}
+		event := Event{
+			Seq:         seq + 1,
+			Task:        task.Name,
+			Description: task.Description,
+			Config:      cfg.Name,
+			Rep:         run.Rep,
+			Count:       task.Count,
+			Warmup:      run.Warmup,
+		}
+		if run.Warmup {
+			event.Count = schedule.Warmup
+		}
+		emit := func(t EventType, e Event) {
+			e.Type, e.Time = t, time.Now()
+			events.Emit(e)
+		}
 
-	startTime := time.Now()
-	err = cmd.Run()
-	result.Duration = time.Since(startTime)
+		// Warmups are only needed for pairs that still have runs to do
+		if run.Warmup && len(samples[key]) >= task.Count {
+			event.Reason = "pair complete"
+			emit(EventRunSkipped, event)
+			continue
+		}
+		if prior, ok := done[runKey{task.Name, cfg.Name, run.Rep}]; ok && !run.Warmup {
+			event.Reason = "already in journal"
+			event.Result = &prior
+			emit(EventRunSkipped, event)
+			continue
+		}
 
-	if err != nil {
-		result.Error = err.Error()
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			result.ExitCode = exitErr.ExitCode()
+		emit(EventRunStarted, event)
+		bench.OnSample = func(s runner.ProcSample) {
+			e := event
+			e.Sample = &s
+			emit(EventSample, e)
+		}
+		result := bench.Run(ctx, task, cfg)
+		result.Repetition = run.Rep
+		result.Sequence = seq + 1
+		event.Result = &result
+		// A run killed by the interrupt says nothing about its config
+		event.Interrupted = ctx.Err() != nil
+		emit(EventRunFinished, event)
+		if run.Warmup || event.Interrupted {
+			continue
+		}
+
+		if err := jrnl.Append(result); err != nil {
+			log.Fatalf("Failed to write journal: %v", err)
+		}
+		samples[key] = append(samples[key], result)
+		if len(samples[key]) == task.Count {
+			summary := runner.Summarize(task.Name, cfg, samples[key])
+			event.Result, event.Summary = nil, &summary
+			emit(EventPairFinished, event)
 		}
 	}
+	interrupted := ctx.Err() != nil
+	events.Emit(Event{Type: EventSweepFinished, Time: time.Now(), Total: len(runs), Interrupted: interrupted})
+	events.Close()
 
-	// Read metrics from agent
-	metrics, err := agentmetrics.ReadFromFile(metricsPath)
-	if err != nil {
-		// Fall back to duration from benchmark harness
-		log.Printf("Warning: Could not read agent metrics: %v", err)
-	} else {
-		// Use metrics from the actual agent process
-		result.MemoryAllocated = metrics.MemoryAllocated
-		result.NumGC = metrics.NumGC
-		result.PauseTimeNs = metrics.PauseTimeNs
+	// Collect results and summaries in plan order, whatever the run order
+	ordered := []runner.BenchmarkResult{}
+	summaries := []runner.BenchmarkSummary{}
+	for _, task := range tasks {
+		for _, cfg := range configs {
+			pair := samples[pairKey{task.Name, cfg.Name}]
+			if len(pair) == 0 {
+				continue
+			}
+			sort.Slice(pair, func(i, j int) bool { return pair[i].Repetition < pair[j].Repetition })
+			ordered = append(ordered, pair...)
+			summaries = append(summaries, runner.Summarize(task.Name, cfg, pair))
+		}
 	}
 
-	return result
-}
+	// Save results to JSON, including partial results when interrupted
+	output := results.Document{
+		Environment: env,
+		Schedule:    schedule,
+		Results:     ordered,
+		Summaries:   summaries,
+		Builds:      builds,
+	}
+	if err := results.Write(*outputFile, output); err != nil {
+		log.Fatalf("Failed to save results: %v", err)
+	}
 
-func saveResults(filename string, results []BenchmarkResult) error {
-	data, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal results: %w", err)
+	if interrupted {
+		fmt.Fprintf(console, "\n\nInterrupted: partial results saved to %s\n", *outputFile)
+		fmt.Fprintf(console, "Run again with -resume to continue from %s\n", *journalFile)
+		jrnl.Close()
+		os.Exit(130)
 	}
 
-	return os.WriteFile(filename, data, 0644)
+	fmt.Fprintf(console, "\n\nResults saved to: %s\n", *outputFile)
+	printSummary(summaries)
 }
 
-func printSummary(results []BenchmarkResult) {
-	fmt.Println("\n=== Summary ===")
+// isFlagSet reports whether the named flag was given on the command line
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
 
-	// Find best configurations
-	var fastestDuration time.Duration = time.Hour
-	var lowestMemory uint64 = ^uint64(0)
-	var fewestGC uint32 = ^uint32(0)
+func printSummary(summaries []runner.BenchmarkSummary) {
+	fmt.Fprintln(console, "\n=== Summary ===")
 
-	var fastestConfig, lowestMemConfig, fewestGCConfig string
+	// Find best configurations by mean across repetitions
+	fastestDuration := math.Inf(1)
+	lowestMemory := math.Inf(1)
+	fewestGC := math.Inf(1)
+	lowestRSS := math.Inf(1)
 
-	for _, r := range results {
-		if r.Error == "" {
-			if r.Duration < fastestDuration {
-				fastestDuration = r.Duration
-				fastestConfig = r.Config.Name
-			}
-			if r.MemoryAllocated < lowestMemory {
-				lowestMemory = r.MemoryAllocated
-				lowestMemConfig = r.Config.Name
-			}
-			if r.NumGC < fewestGC {
-				fewestGC = r.NumGC
-				fewestGCConfig = r.Config.Name
-			}
+	var fastest, lowestMem, fewestGCs, lowestPeakRSS runner.BenchmarkSummary
+
+	for _, s := range summaries {
+		if s.Duration.N == 0 {
+			continue
 		}
+		if s.Duration.Mean < fastestDuration {
+			fastestDuration = s.Duration.Mean
+			fastest = s
+		}
+		if s.MemoryAllocated.Mean < lowestMemory {
+			lowestMemory = s.MemoryAllocated.Mean
+			lowestMem = s
+		}
+		if s.NumGC.Mean < fewestGC {
+			fewestGC = s.NumGC.Mean
+			fewestGCs = s
+		}
+		if s.PeakRSS.Mean < lowestRSS {
+			lowestRSS = s.PeakRSS.Mean
+			lowestPeakRSS = s
+		}
+	}
+
+	if fastest.Duration.N == 0 {
+		fmt.Fprintln(console, "No successful runs")
+		return
 	}
 
-	fmt.Printf("Fastest execution: %s (%v)\n", fastestConfig, fastestDuration)
-	fmt.Printf("Lowest memory: %s (%.2f MB)\n", lowestMemConfig, float64(lowestMemory)/(1024*1024))
-	fmt.Printf("Fewest GC runs: %s (%d runs)\n", fewestGCConfig, fewestGC)
+	fmt.Fprintf(console, "Fastest execution: %s/%s (%v, 95%% CI %v..%v)\n",
+		fastest.Task, fastest.Config.Name,
+		time.Duration(fastest.Duration.Mean),
+		time.Duration(fastest.Duration.CILow),
+		time.Duration(fastest.Duration.CIHigh))
+	fmt.Fprintf(console, "Lowest memory: %s/%s (%.2f MB, 95%% CI %.2f..%.2f MB)\n",
+		lowestMem.Task, lowestMem.Config.Name,
+		lowestMem.MemoryAllocated.Mean/(1024*1024),
+		lowestMem.MemoryAllocated.CILow/(1024*1024),
+		lowestMem.MemoryAllocated.CIHigh/(1024*1024))
+	fmt.Fprintf(console, "Fewest GC runs: %s/%s (%.1f runs, 95%% CI %.1f..%.1f)\n",
+		fewestGCs.Task, fewestGCs.Config.Name,
+		fewestGCs.NumGC.Mean,
+		fewestGCs.NumGC.CILow,
+		fewestGCs.NumGC.CIHigh)
+	fmt.Fprintf(console, "Lowest peak RSS: %s/%s (%.2f MB, 95%% CI %.2f..%.2f MB)\n",
+		lowestPeakRSS.Task, lowestPeakRSS.Config.Name,
+		lowestPeakRSS.PeakRSS.Mean/(1024*1024),
+		lowestPeakRSS.PeakRSS.CILow/(1024*1024),
+		lowestPeakRSS.PeakRSS.CIHigh/(1024*1024))
 }
 
 func init() {
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/runner"
+	"github.com/natalie/go-flags-eval/internal/stats"
+)
+
+// metric extracts one lower-is-better quantity from a successful run
+type metric struct {
+	name   string
+	value  func(runner.BenchmarkResult) float64
+	format func(float64) string
+}
+
+var allMetrics = []metric{
+	{"duration", func(r runner.BenchmarkResult) float64 { return float64(r.Duration) }, formatNanos},
+	{"cpu", func(r runner.BenchmarkResult) float64 { return float64(r.Resources.UserCPU + r.Resources.SystemCPU) }, formatNanos},
+	{"pause", func(r runner.BenchmarkResult) float64 { return float64(r.PauseTimeNs) }, formatNanos},
+	{"memory", func(r runner.BenchmarkResult) float64 { return float64(r.MemoryAllocated) }, formatBytes},
+	{"rss", func(r runner.BenchmarkResult) float64 { return float64(r.Resources.PeakRSS) }, formatBytes},
+	{"gc", func(r runner.BenchmarkResult) float64 { return float64(r.NumGC) }, func(v float64) string { return fmt.Sprintf("%.1f", v) }},
+}
+
+func lookupMetric(name string) (metric, error) {
+	names := []string{}
+	for _, m := range allMetrics {
+		if m.name == name {
+			return m, nil
+		}
+		names = append(names, m.name)
+	}
+	return metric{}, fmt.Errorf("unknown metric %q (want one of %s)", name, strings.Join(names, ", "))
+}
+
+func formatNanos(v float64) string {
+	d := time.Duration(v)
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	default:
+		return d.String()
+	}
+}
+
+func formatBytes(v float64) string {
+	return fmt.Sprintf("%.2fMB", v/(1024*1024))
+}
+
+// pairKey identifies a task/config pair across the two files
+type pairKey struct {
+	task   string
+	config string
+}
+
+func (k pairKey) String() string {
+	// Legacy results files do not record the task
+	if k.task == "" {
+		return k.config
+	}
+	return k.task + "/" + k.config
+}
+
+// pairRuns are the runs of one pair in one file
+type pairRuns struct {
+	ok       []runner.BenchmarkResult
+	failures int
+}
+
+// group collects results by pair, keeping the order of first appearance
+func group(results []runner.BenchmarkResult) ([]pairKey, map[pairKey]*pairRuns) {
+	keys := []pairKey{}
+	pairs := map[pairKey]*pairRuns{}
+	for _, r := range results {
+		key := pairKey{r.Task, r.Config.Name}
+		p, ok := pairs[key]
+		if !ok {
+			p = &pairRuns{}
+			pairs[key] = p
+			keys = append(keys, key)
+		}
+		if r.Error != "" {
+			p.failures++
+			continue
+		}
+		p.ok = append(p.ok, r)
+	}
+	return keys, pairs
+}
+
+// Comparison is one metric of one pair in both files
+type Comparison struct {
+	Pair        string
+	Metric      string
+	Old, New    stats.Summary
+	Delta       float64 // Relative change of the mean, e.g. 0.05 for +5%
+	P           float64
+	Significant bool
+	Regression  bool
+	// Too few runs for any difference to be significant at -alpha
+	Inconclusive bool `json:",omitempty"`
+}
+
+func compareSamples(key pairKey, m metric, before, after []runner.BenchmarkResult, test func(a, b []float64) float64) Comparison {
+	a := make([]float64, len(before))
+	for i, r := range before {
+		a[i] = m.value(r)
+	}
+	b := make([]float64, len(after))
+	for i, r := range after {
+		b[i] = m.value(r)
+	}
+
+	c := Comparison{
+		Pair:   key.String(),
+		Metric: m.name,
+		Old:    stats.Summarize(a),
+		New:    stats.Summarize(b),
+		P:      test(a, b),
+	}
+	if c.Old.Mean != 0 {
+		c.Delta = c.New.Mean/c.Old.Mean - 1
+	}
+	return c
+}
+
+// minReachableP returns the smallest p-value test can give for samples
+// of n1 and n2 values, which it gives when the samples lie far apart
+func minReachableP(test func(a, b []float64) float64, n1, n2 int) float64 {
+	a, b := make([]float64, n1), make([]float64, n2)
+	for i := range a {
+		a[i] = float64(i)
+	}
+	for i := range b {
+		b[i] = 1e12 + float64(i)
+	}
+	return test(a, b)
+}
+
+// geomean returns the geometric mean of the positive values
+func geomean(values []float64) float64 {
+	sum, n := 0.0, 0
+	for _, v := range values {
+		if v > 0 {
+			sum += math.Log(v)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return math.Exp(sum / float64(n))
+}
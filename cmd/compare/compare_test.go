@@ -0,0 +1,32 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/natalie/go-flags-eval/internal/stats"
+)
+
+func TestMinReachableP(t *testing.T) {
+	tests := []struct {
+		name   string
+		test   func(a, b []float64) float64
+		n1, n2 int
+		want   float64
+	}{
+		{"utest, one run each", stats.MannWhitneyU, 1, 1, 1},
+		{"utest, 3+3", stats.MannWhitneyU, 3, 3, 0.1},
+		{"utest, 4+4", stats.MannWhitneyU, 4, 4, 2.0 / 70},
+		{"utest, 5+5", stats.MannWhitneyU, 5, 5, 2.0 / 252},
+		{"utest, no baseline runs", stats.MannWhitneyU, 0, 10, 1},
+		{"ttest, one run", stats.WelchT, 1, 10, 1},
+	}
+	for _, tt := range tests {
+		if got := minReachableP(tt.test, tt.n1, tt.n2); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: minReachableP = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := minReachableP(stats.WelchT, 2, 2); got >= 0.05 {
+		t.Errorf("ttest, 2+2: minReachableP = %v, want below 0.05", got)
+	}
+}
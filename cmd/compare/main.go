@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/natalie/go-flags-eval/internal/results"
+	"github.com/natalie/go-flags-eval/internal/runner"
+	"github.com/natalie/go-flags-eval/internal/stats"
+)
+
+var (
+	metricList = flag.String("metrics", "duration,cpu,memory,rss", "Comma-separated metrics to compare (duration, cpu, pause, memory, rss, gc)")
+	threshold  = flag.Float64("threshold", 5, "Largest tolerated significant increase of a metric, in percent")
+	thresholds = flag.String("thresholds", "", "Per-metric thresholds overriding -threshold, e.g. \"duration=3,rss=10\"")
+	alpha      = flag.Float64("alpha", 0.05, "Significance level: differences with a higher p-value are treated as noise")
+	testName   = flag.String("test", "utest", "Significance test: utest (Mann-Whitney U) or ttest (Welch's t-test)")
+	taskFilter = flag.String("task", "", "Comma-separated tasks to compare (default: all)")
+	cfgFilter  = flag.String("config", "", "Comma-separated configs to compare, e.g. the production flag set (default: all)")
+	jsonOutput = flag.String("json", "", "Also write every comparison to this JSON file")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: compare [flags] baseline.json new.json\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Compares two benchmark results files and exits with status 1 if any\nmetric regressed beyond its threshold, or 2 on errors and when a\npair has too few runs for any difference to be significant.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var test func(a, b []float64) float64
+	switch *testName {
+	case "utest":
+		test = stats.MannWhitneyU
+	case "ttest":
+		test = stats.WelchT
+	default:
+		fatalf("Unknown -test=%s (want utest or ttest)", *testName)
+	}
+
+	metrics := []metric{}
+	for _, name := range strings.Split(*metricList, ",") {
+		m, err := lookupMetric(strings.TrimSpace(name))
+		if err != nil {
+			fatalf("Invalid -metrics: %v", err)
+		}
+		metrics = append(metrics, m)
+	}
+	limits, err := parseThresholds(*thresholds)
+	if err != nil {
+		fatalf("Invalid -thresholds: %v", err)
+	}
+
+	oldResults, err := loadResults(flag.Arg(0))
+	if err != nil {
+		fatalf("Failed to load baseline: %v", err)
+	}
+	newResults, err := loadResults(flag.Arg(1))
+	if err != nil {
+		fatalf("Failed to load new results: %v", err)
+	}
+
+	oldKeys, oldPairs := group(filter(oldResults))
+	newKeys, newPairs := group(filter(newResults))
+
+	// Pairs are matched by task and config name, in baseline order
+	matched := []pairKey{}
+	for _, key := range oldKeys {
+		if _, ok := newPairs[key]; ok {
+			matched = append(matched, key)
+		} else {
+			fmt.Printf("Only in baseline: %s\n", key)
+		}
+	}
+	for _, key := range newKeys {
+		if _, ok := oldPairs[key]; !ok {
+			fmt.Printf("Only in new results: %s\n", key)
+		}
+	}
+	if len(matched) == 0 {
+		fatalf("No task/config pairs in common")
+	}
+
+	regressions := []string{}
+	inconclusive := map[pairKey]bool{}
+	for _, key := range matched {
+		// More failures than the baseline, relative to the successful runs
+		before, after := oldPairs[key], newPairs[key]
+		if after.failures > 0 && after.failures*len(before.ok) > before.failures*len(after.ok) {
+			regressions = append(regressions, fmt.Sprintf("%s: %d failed runs (baseline %d)", key, after.failures, before.failures))
+		}
+		// With too few runs the gate would pass whatever the difference
+		if p := minReachableP(test, len(before.ok), len(after.ok)); p >= *alpha {
+			fmt.Printf("Too few runs: %s has %d+%d successful runs, whose smallest p-value is %.3f, not below -alpha=%g\n",
+				key, len(before.ok), len(after.ok), p, *alpha)
+			inconclusive[key] = true
+		}
+	}
+
+	all := []Comparison{}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	for _, m := range metrics {
+		limit := limits[m.name]
+		fmt.Fprintf(tw, "\n%s\told\tnew\tdelta\n", m.name)
+
+		var oldMeans, newMeans []float64
+		for _, key := range matched {
+			c := compareSamples(key, m, oldPairs[key].ok, newPairs[key].ok, test)
+			c.Significant = c.P < *alpha
+			c.Regression = c.Significant && c.Delta*100 > limit
+			c.Inconclusive = inconclusive[key]
+			all = append(all, c)
+
+			delta := "~"
+			if c.Significant {
+				delta = fmt.Sprintf("%+.2f%%", c.Delta*100)
+			} else if c.Inconclusive {
+				delta = "?"
+			}
+			if c.Regression {
+				regressions = append(regressions, fmt.Sprintf("%s: %s %+.2f%% (p=%.3f, threshold %g%%)", key, m.name, c.Delta*100, c.P, limit))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s (p=%.3f n=%d+%d)\n",
+				key, formatSummary(m, c.Old), formatSummary(m, c.New), delta, c.P, c.Old.N, c.New.N)
+
+			// Pairs are dropped jointly, so that both geomeans cover the same pairs
+			if c.Old.N > 0 && c.New.N > 0 && c.Old.Mean > 0 && c.New.Mean > 0 {
+				oldMeans = append(oldMeans, c.Old.Mean)
+				newMeans = append(newMeans, c.New.Mean)
+			}
+		}
+
+		if len(oldMeans) > 1 {
+			oldGeo, newGeo := geomean(oldMeans), geomean(newMeans)
+			delta := ""
+			if oldGeo > 0 {
+				delta = fmt.Sprintf("%+.2f%%", (newGeo/oldGeo-1)*100)
+			}
+			fmt.Fprintf(tw, "[Geo mean]\t%s\t%s\t%s\n", m.format(oldGeo), m.format(newGeo), delta)
+		}
+	}
+	tw.Flush()
+
+	if *jsonOutput != "" {
+		data, err := json.MarshalIndent(all, "", "  ")
+		if err == nil {
+			err = os.WriteFile(*jsonOutput, data, 0644)
+		}
+		if err != nil {
+			fatalf("Failed to write %s: %v", *jsonOutput, err)
+		}
+	}
+
+	if len(regressions) > 0 {
+		fmt.Printf("\nFAIL: %d regression(s)\n", len(regressions))
+		for _, r := range regressions {
+			fmt.Printf("  %s\n", r)
+		}
+		os.Exit(1)
+	}
+	if len(inconclusive) > 0 {
+		fmt.Printf("\nINCONCLUSIVE: %d pair(s) with too few runs to detect a regression, see above\n", len(inconclusive))
+		os.Exit(2)
+	}
+	fmt.Println("\nPASS: no significant regressions")
+}
+
+func fatalf(format string, args ...any) {
+	log.Printf(format, args...)
+	os.Exit(2)
+}
+
+// formatSummary renders a mean with its 95% confidence interval as a
+// percentage, like benchstat
+func formatSummary(m metric, s stats.Summary) string {
+	if s.N == 0 {
+		return "-"
+	}
+	if s.N == 1 {
+		return m.format(s.Mean)
+	}
+	return fmt.Sprintf("%s ± %.0f%%", m.format(s.Mean), s.RelativeCI()*100)
+}
+
+// parseThresholds applies "metric=percent" overrides to -threshold
+func parseThresholds(s string) (map[string]float64, error) {
+	limits := map[string]float64{}
+	for _, m := range allMetrics {
+		limits[m.name] = *threshold
+	}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: expected metric=percent", item)
+		}
+		m, err := lookupMetric(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("%q: invalid percentage", item)
+		}
+		limits[m.name] = v
+	}
+	return limits, nil
+}
+
+// loadResults reads the results of a benchmark file of any schema version
+func loadResults(filename string) ([]runner.BenchmarkResult, error) {
+	doc, err := results.Read(filename)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Results, nil
+}
+
+// filter keeps the results selected by -task and -config
+func filter(results []runner.BenchmarkResult) []runner.BenchmarkResult {
+	tasks, configs := splitSet(*taskFilter), splitSet(*cfgFilter)
+	out := []runner.BenchmarkResult{}
+	for _, r := range results {
+		if (len(tasks) == 0 || tasks[r.Task]) && (len(configs) == 0 || configs[r.Config.Name]) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func splitSet(list string) map[string]bool {
+	set := map[string]bool{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			set[item] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/agentmetrics"
+)
+
+var (
+	workloadName  = flag.String("workload", "mixed", "Workload profile: "+workloadNames())
+	duration      = flag.Duration("duration", 30*time.Second, "How long to run the workload after warmup")
+	warmup        = flag.Duration("warmup", 0, "Time to run the workload before measuring")
+	workers       = flag.Int("workers", 0, "Concurrent workers (0 = GOMAXPROCS)")
+	heapMB        = flag.Int("heap-mb", 64, "Size of the long-lived heap of the heap and mixed workloads (MB)")
+	maxProcs      = flag.Int("maxprocs", 0, "Set GOMAXPROCS (0 = keep the GOMAXPROCS environment variable or default)")
+	memLimit      = flag.Int64("memlimit", 0, "Set the soft memory limit in MB (0 = keep GOMEMLIMIT or unlimited)")
+	gcPercent     = flag.Int("gcpercent", 100, "Set GOGC, -1 disables the GC (unset = keep the GOGC environment variable)")
+	verbose       = flag.Bool("verbose", false, "Print progress every second")
+	metricsOutput = flag.String("metrics-output", "", "File to write performance metrics (JSON)")
+	sampleEvery   = flag.Duration("sample-interval", 100*time.Millisecond, "How often to sample heap and GC into the metrics timeline (0 = no timeline)")
+)
+
+func main() {
+	flag.Parse()
+
+	w, err := lookupWorkload(*workloadName)
+	if err != nil {
+		log.Fatalf("Invalid -workload: %v", err)
+	}
+	if *duration <= 0 {
+		log.Fatalf("Invalid -duration: must be positive")
+	}
+
+	// Flags override the environment only when given, so that the runner's
+	// GOMAXPROCS/GOMEMLIMIT/GOGC apply when eval runs as a benchmark task
+	if *maxProcs > 0 {
+		runtime.GOMAXPROCS(*maxProcs)
+	}
+	if *memLimit > 0 {
+		debug.SetMemoryLimit(*memLimit * 1024 * 1024)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "gcpercent" {
+			debug.SetGCPercent(*gcPercent)
+		}
+	})
+	if *workers == 0 {
+		*workers = runtime.GOMAXPROCS(-1)
+	}
+
+	var sampler *agentmetrics.Sampler
+	if *metricsOutput != "" && *sampleEvery > 0 {
+		sampler = agentmetrics.StartSampler(*sampleEvery)
+	}
+
+	// Report configuration
+	fmt.Printf("=== Go Flags Evaluation ===\n")
+	fmt.Printf("GOMAXPROCS: %d (available CPUs: %d)\n", runtime.GOMAXPROCS(-1), runtime.NumCPU())
+	if limit := debug.SetMemoryLimit(-1); limit == math.MaxInt64 {
+		fmt.Printf("GOMEMLIMIT: unlimited\n")
+	} else {
+		fmt.Printf("GOMEMLIMIT: %d MB\n", limit/(1024*1024))
+	}
+	gcVal := debug.SetGCPercent(-1)
+	debug.SetGCPercent(gcVal)
+	if gcVal < 0 {
+		fmt.Printf("GOGC: off\n")
+	} else {
+		fmt.Printf("GOGC: %d%%\n", gcVal)
+	}
+	fmt.Printf("Workload: %s (%s)\n", w.name, w.description)
+	fmt.Printf("Workers: %d\n", *workers)
+	fmt.Printf("Duration: %v\n", *duration)
+	if *warmup > 0 {
+		fmt.Printf("Warmup: %v\n", *warmup)
+	}
+	fmt.Printf("\n")
+
+	if w.setup != nil {
+		w.setup(*workers, *heapMB)
+	}
+	rngs := make([]*rand.Rand, *workers)
+	for i := range rngs {
+		rngs[i] = rand.New(rand.NewSource(int64(i) + 1))
+	}
+
+	var wg sync.WaitGroup
+	var done int
+	var doneMu sync.Mutex
+	iteration := func() {
+		for i := 0; i < *workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				w.unit(i, rngs[i])
+			}(i)
+		}
+		wg.Wait()
+		// On a single P, the hand-offs between this goroutine and the
+		// workers keep the scheduler's runnext slot busy, which starves
+		// the sampler and progress goroutines; yield so they get to run
+		runtime.Gosched()
+		doneMu.Lock()
+		done++
+		doneMu.Unlock()
+	}
+
+	if *verbose {
+		stop := make(chan struct{})
+		defer close(stop)
+		go progress(stop, func() int {
+			doneMu.Lock()
+			defer doneMu.Unlock()
+			return done
+		})
+	}
+
+	fmt.Printf("Starting workload...\n")
+	metrics := agentmetrics.RunSteadyState(*warmup, *duration, iteration)
+	metrics.TasksCompleted = metrics.SteadyState.Iterations * *workers
+	metrics.Custom = map[string]any{
+		"workload": w.name,
+		"workers":  *workers,
+	}
+
+	// Print results
+	fmt.Printf("\n=== Results ===\n")
+	fmt.Printf("Duration: %v\n", metrics.Duration.Round(time.Millisecond))
+	fmt.Printf("Memory Allocated: %.2f MB\n", float64(metrics.MemoryAllocated)/(1024*1024))
+	fmt.Printf("Heap Allocated: %.2f MB\n", float64(metrics.HeapAllocated)/(1024*1024))
+	fmt.Printf("Number of GC runs: %d\n", metrics.NumGC)
+	fmt.Printf("Total GC pause time: %v\n", time.Duration(metrics.PauseTimeNs))
+	if metrics.NumGC > 0 {
+		fmt.Printf("Average GC pause time: %v\n", time.Duration(metrics.PauseTimeNs/uint64(metrics.NumGC)))
+	}
+	fmt.Printf("Active goroutines: %d\n", metrics.Goroutines)
+	fmt.Printf("GOMAXPROCS: %d\n", runtime.GOMAXPROCS(-1))
+	fmt.Printf("Units completed: %d\n", metrics.TasksCompleted)
+	metrics.SteadyState.Print()
+
+	// Write metrics to file if requested
+	if *metricsOutput != "" {
+		metrics.Runtime = agentmetrics.ReadRuntimeMetrics()
+		metrics.Timeline = sampler.Stop()
+		if err := metrics.WriteToFile(*metricsOutput); err != nil {
+			log.Printf("Failed to write metrics: %v", err)
+		}
+	}
+}
+
+// progress prints the iterations completed and the heap every second
+// until stop is closed
+func progress(stop <-chan struct{}, iterations func() int) {
+	start := time.Now()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			var ms runtime.MemStats
+			runtime.ReadMemStats(&ms)
+			fmt.Printf("[%v] %d iterations, heap %.2f MB, GC runs %d\n",
+				time.Since(start).Round(time.Second), iterations(),
+				float64(ms.HeapAlloc)/(1024*1024), ms.NumGC)
+		}
+	}
+}
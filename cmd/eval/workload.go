@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// workload is one profile of synthetic work. unit does one piece of work
+// for worker w; every worker calls it concurrently with its own rng.
+type workload struct {
+	name        string
+	description string
+	setup       func(workers int, heapMB int) // Before warmup, outside the measurement
+	unit        func(w int, rng *rand.Rand)
+}
+
+var workloads = map[string]*workload{
+	"cpu": {
+		name:        "cpu",
+		description: "SHA-256 over a per-worker buffer, no allocation",
+		setup:       allocCPUBuffers,
+		unit:        cpuUnit,
+	},
+	"memory": {
+		name:        "memory",
+		description: "Short-lived slices and maps, allocation-heavy",
+		unit:        memoryUnit,
+	},
+	"heap": {
+		name:        "heap",
+		description: "Large long-lived pointer graph, partly replaced and traversed",
+		setup:       buildHeap,
+		unit:        heapUnit,
+	},
+	"churn": {
+		name:        "churn",
+		description: "Short-lived goroutines exchanging results over channels",
+		unit:        churnUnit,
+	},
+	"mixed": {
+		name:        "mixed",
+		description: "Workers rotate through cpu, memory, heap and churn",
+		setup:       setupMixed,
+		unit:        mixedUnit,
+	},
+}
+
+// workloadNames lists the profiles for flag help and error messages
+func workloadNames() string {
+	names := make([]string, 0, len(workloads))
+	for name := range workloads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func lookupWorkload(name string) (*workload, error) {
+	w, ok := workloads[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown workload %q (want one of %s)", name, workloadNames())
+	}
+	return w, nil
+}
+
+// cpuBuffers are the per-worker inputs of cpuUnit
+var cpuBuffers [][]byte
+
+func allocCPUBuffers(workers int, heapMB int) {
+	cpuBuffers = make([][]byte, workers)
+	for w := range cpuBuffers {
+		cpuBuffers[w] = make([]byte, 64*1024)
+	}
+}
+
+// cpuUnit hashes 1 MiB, feeding every digest back into the buffer so the
+// work cannot be skipped
+func cpuUnit(w int, rng *rand.Rand) {
+	buf := cpuBuffers[w]
+	for i := 0; i < 16; i++ {
+		sum := sha256.Sum256(buf)
+		copy(buf[(i*sha256.Size)%len(buf):], sum[:])
+	}
+}
+
+// sink consumes the result of every unit, so the work cannot be optimized
+// away
+var sink atomic.Int64
+
+// memoryUnit allocates about 2 MB of objects of mixed sizes that all die
+// young
+func memoryUnit(w int, rng *rand.Rand) {
+	chunks := make([][]byte, 0, 512)
+	for i := 0; i < 512; i++ {
+		chunk := make([]byte, 64+rng.Intn(4096))
+		chunk[0] = byte(i)
+		chunks = append(chunks, chunk)
+	}
+	index := make(map[int][]byte, len(chunks))
+	for i, chunk := range chunks {
+		index[i*len(chunk)] = chunk
+	}
+	sink.Store(int64(len(index)))
+}
+
+// node is one element of the long-lived pointer graph
+type node struct {
+	left, right *node
+	payload     [6]int64
+}
+
+const treeDepth = 10 // 1023 nodes, about 80 KB per tree
+
+// heapShards holds each worker's share of the long-lived trees, so that
+// workers replace trees without locking
+var heapShards [][]*node
+
+// buildHeap grows the long-lived graph to about heapMB megabytes
+func buildHeap(workers int, heapMB int) {
+	const treeBytes = (1<<treeDepth - 1) * 64
+	trees := max(heapMB<<20/treeBytes, workers)
+	heapShards = make([][]*node, workers)
+	for i := 0; i < trees; i++ {
+		heapShards[i%workers] = append(heapShards[i%workers], buildTree(treeDepth, int64(i)))
+	}
+}
+
+func buildTree(depth int, seed int64) *node {
+	if depth == 0 {
+		return nil
+	}
+	n := &node{left: buildTree(depth-1, seed*2), right: buildTree(depth-1, seed*2+1)}
+	n.payload[0] = seed
+	return n
+}
+
+func walkTree(n *node) int64 {
+	if n == nil {
+		return 0
+	}
+	return n.payload[0] + walkTree(n.left) + walkTree(n.right)
+}
+
+// heapUnit replaces one of the worker's trees, turning the old one into
+// garbage, and traverses another, so every GC cycle has a large live graph
+// to mark
+func heapUnit(w int, rng *rand.Rand) {
+	shard := heapShards[w]
+	shard[rng.Intn(len(shard))] = buildTree(treeDepth, rng.Int63())
+	sink.Store(walkTree(shard[rng.Intn(len(shard))]))
+}
+
+// churnUnit starts 64 goroutines that each do a little work and send the
+// result back
+func churnUnit(w int, rng *rand.Rand) {
+	const goroutines = 64
+	results := make(chan int, goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(n int) {
+			sum := 0
+			for j := 0; j < 1000; j++ {
+				sum += j * n
+			}
+			results <- sum
+		}(i)
+	}
+	total := 0
+	for i := 0; i < goroutines; i++ {
+		total += <-results
+	}
+	sink.Store(int64(total))
+}
+
+var mixedUnits = []func(int, *rand.Rand){cpuUnit, memoryUnit, heapUnit, churnUnit}
+
+// mixedCounts is how many units each worker has run, so that a worker
+// moves on to the next profile every time
+var mixedCounts []int
+
+func setupMixed(workers int, heapMB int) {
+	allocCPUBuffers(workers, heapMB)
+	buildHeap(workers, heapMB)
+	mixedCounts = make([]int, workers)
+}
+
+func mixedUnit(w int, rng *rand.Rand) {
+	mixedUnits[(w+mixedCounts[w])%len(mixedUnits)](w, rng)
+	mixedCounts[w]++
+}
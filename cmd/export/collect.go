@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/agentmetrics"
+	"github.com/natalie/go-flags-eval/internal/expfmt"
+	"github.com/natalie/go-flags-eval/internal/results"
+	"github.com/natalie/go-flags-eval/internal/runner"
+)
+
+// collectResults adds the environment of a results file and every run in
+// it. Failed runs only report goflags_run_success.
+func collectResults(fs *expfmt.Families, doc results.Document) {
+	env := doc.Environment
+	fs.Add("goflags_results", expfmt.Info, "Where and with which toolchain the results were taken", []expfmt.Label{
+		{Name: "hostname", Value: env.Hostname},
+		{Name: "go_version", Value: env.GoVersion},
+		{Name: "goos", Value: env.GOOS},
+		{Name: "goarch", Value: env.GOARCH},
+		{Name: "cpu_model", Value: env.CPUModel},
+		{Name: "kernel", Value: env.Kernel},
+		{Name: "git_commit", Value: env.GitCommit},
+	}, 1)
+	if !env.Timestamp.IsZero() {
+		fs.Add("goflags_results_timestamp_seconds", expfmt.Gauge, "When the benchmark run started, in seconds since the epoch", nil,
+			float64(env.Timestamp.UnixNano())/1e9)
+	}
+
+	repetitions := distinctRepetitions(doc.Results)
+	for i, r := range doc.Results {
+		labels := configLabels(r.Task, r.Config, repetitions[i])
+		if r.Error != "" {
+			fs.Add("goflags_run_success", expfmt.Gauge, "1 if the run succeeded, 0 if it failed", labels, 0)
+			continue
+		}
+		fs.Add("goflags_run_success", expfmt.Gauge, "1 if the run succeeded, 0 if it failed", labels, 1)
+		fs.Add("goflags_run_duration_seconds", expfmt.Gauge, "Wall time of the run", labels, r.Duration.Seconds())
+		fs.Add("goflags_run_memory_allocated_bytes", expfmt.Gauge, "Bytes allocated during the run", labels, float64(r.MemoryAllocated))
+		fs.Add("goflags_run_gc_cycles", expfmt.Gauge, "GC cycles during the run", labels, float64(r.NumGC))
+		fs.Add("goflags_run_gc_pause_seconds", expfmt.Gauge, "Total stop-the-world GC pause time of the run", labels, float64(r.PauseTimeNs)/1e9)
+		fs.Add("goflags_run_peak_rss_bytes", expfmt.Gauge, "Peak resident set size of the agent process", labels, float64(r.Resources.PeakRSS))
+		fs.Add("goflags_run_cpu_seconds", expfmt.Gauge, "CPU time of the agent process by mode", with(labels, "mode", "user"), r.Resources.UserCPU.Seconds())
+		fs.Add("goflags_run_cpu_seconds", expfmt.Gauge, "CPU time of the agent process by mode", with(labels, "mode", "system"), r.Resources.SystemCPU.Seconds())
+		collectAgent(fs, labels, r.SteadyState, r.Runtime)
+	}
+}
+
+// distinctRepetitions returns the repetition label of each result. Files
+// written before repetitions were recorded, such as the bare arrays of the
+// first versions, give every run repetition 0, which would export
+// duplicate series. The runs of a task/config pair whose repetitions are
+// missing or not unique are numbered from 1 in file order instead.
+func distinctRepetitions(rs []runner.BenchmarkResult) []int {
+	type pair struct{ task, config string }
+	type run struct {
+		pair
+		repetition int
+	}
+	seen := map[run]bool{}
+	renumber := map[pair]bool{}
+	for _, r := range rs {
+		k := run{pair{r.Task, r.Config.Name}, r.Repetition}
+		if seen[k] || r.Repetition < 1 {
+			renumber[k.pair] = true
+		}
+		seen[k] = true
+	}
+
+	repetitions := make([]int, len(rs))
+	counts := map[pair]int{}
+	for i, r := range rs {
+		p := pair{r.Task, r.Config.Name}
+		repetitions[i] = r.Repetition
+		if renumber[p] {
+			counts[p]++
+			repetitions[i] = counts[p]
+		}
+	}
+	return repetitions
+}
+
+// collectMetrics adds a metrics file written by an agent
+func collectMetrics(fs *expfmt.Families, m *agentmetrics.Metrics, labels []expfmt.Label) {
+	fs.Add("goflags_run_duration_seconds", expfmt.Gauge, "Wall time of the run", labels, m.Duration.Seconds())
+	fs.Add("goflags_run_memory_allocated_bytes", expfmt.Gauge, "Bytes allocated during the run", labels, float64(m.MemoryAllocated))
+	fs.Add("goflags_run_gc_cycles", expfmt.Gauge, "GC cycles during the run", labels, float64(m.NumGC))
+	fs.Add("goflags_run_gc_pause_seconds", expfmt.Gauge, "Total stop-the-world GC pause time of the run", labels, float64(m.PauseTimeNs)/1e9)
+	fs.Add("goflags_agent_heap_allocated_bytes", expfmt.Gauge, "Heap in use when the agent finished", labels, float64(m.HeapAllocated))
+	fs.Add("goflags_agent_goroutines", expfmt.Gauge, "Goroutines when the agent finished", labels, float64(m.Goroutines))
+	fs.Add("goflags_agent_tasks_completed", expfmt.Gauge, "Units of work the agent completed", labels, float64(m.TasksCompleted))
+	fs.Add("goflags_agent_files_processed", expfmt.Gauge, "Files the agent processed", labels, float64(m.FilesProcessed))
+
+	phases := make([]string, 0, len(m.Phases))
+	for name := range m.Phases {
+		phases = append(phases, name)
+	}
+	sort.Strings(phases)
+	for _, name := range phases {
+		fs.Add("goflags_agent_phase_seconds", expfmt.Gauge, "Time spent in each phase of the measured passes", with(labels, "phase", name), m.Phases[name].Seconds())
+	}
+
+	collectAgent(fs, labels, m.SteadyState, m.Runtime)
+}
+
+// collectAgent adds what both results and agent metrics files carry from
+// the agent: steady-state throughput and latency, and runtime/metrics
+func collectAgent(fs *expfmt.Families, labels []expfmt.Label, ss *agentmetrics.SteadyState, rm *agentmetrics.RuntimeMetrics) {
+	if ss != nil {
+		fs.Add("goflags_steady_ops_per_second", expfmt.Gauge, "Workload iterations per second after warmup", labels, ss.OpsPerSec)
+		for _, q := range []struct {
+			quantile string
+			latency  time.Duration
+		}{
+			{"0.5", ss.LatencyP50},
+			{"0.9", ss.LatencyP90},
+			{"0.99", ss.LatencyP99},
+			{"0.999", ss.LatencyP999},
+		} {
+			fs.Add("goflags_steady_latency_seconds", expfmt.Summary, "Latency of single workload iterations after warmup",
+				with(labels, "quantile", q.quantile), q.latency.Seconds())
+		}
+		fs.AddSample("goflags_steady_latency_seconds", expfmt.Summary, "", expfmt.Sample{Suffix: "_count", Labels: labels, Value: float64(ss.Iterations)})
+		fs.Add("goflags_steady_latency_max_seconds", expfmt.Gauge, "Slowest workload iteration after warmup", labels, ss.LatencyMax.Seconds())
+	}
+
+	if rm == nil {
+		return
+	}
+	fs.Add("goflags_gc_cpu_fraction", expfmt.Gauge, "Share of GOMAXPROCS times wall time the GC used", labels, rm.GCCPUFraction())
+	fs.Add("goflags_gc_pause_p99_seconds", expfmt.Gauge, "99th percentile stop-the-world GC pause, as a bucket upper bound", labels, rm.GCPauses().Quantile(0.99))
+	fs.Add("goflags_sched_latency_p99_seconds", expfmt.Gauge, "99th percentile time goroutines waited to run, as a bucket upper bound", labels,
+		rm.Histograms[agentmetrics.MetricSchedLatency].Quantile(0.99))
+
+	values := make(map[string]float64, len(rm.Uint64)+len(rm.Float64))
+	for name, v := range rm.Uint64 {
+		values[name] = float64(v)
+	}
+	for name, v := range rm.Float64 {
+		values[name] = v
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		typ, help := expfmt.Gauge, name
+		if desc, known := runtimeDescriptions()[name]; known {
+			help = desc.Description
+			if desc.Cumulative {
+				typ = expfmt.Counter
+			}
+		}
+		fs.Add(runtimeName(name), typ, help, labels, values[name])
+	}
+}
+
+// runtimeDescriptions describes the runtime/metrics of the Go version the
+// exporter was built with. Metrics only newer agents know are exported as
+// gauges.
+var runtimeDescriptions = sync.OnceValue(func() map[string]metrics.Description {
+	descs := map[string]metrics.Description{}
+	for _, d := range metrics.All() {
+		descs[d.Name] = d
+	}
+	return descs
+})
+
+// runtimeName names a runtime/metrics value the way the Prometheus Go
+// client does, e.g. "/gc/heap/goal:bytes" becomes go_gc_heap_goal_bytes,
+// so that dashboards built for it work on agent runs too
+func runtimeName(name string) string {
+	path, unit, _ := strings.Cut(strings.TrimPrefix(name, "/"), ":")
+	return expfmt.SanitizeName("go_" + path + "_" + unit)
+}
+
+// configLabels are the labels of every series of a run: the task, the
+// config and the runtime flags it set, in the form of their environment
+// variables or "default" when unset, and the repetition
+func configLabels(task string, cfg runner.BenchmarkConfig, repetition int) []expfmt.Label {
+	maxProcs, memLimit, gogc := "default", "default", "off"
+	if cfg.MaxProcs > 0 {
+		maxProcs = strconv.Itoa(cfg.MaxProcs)
+	}
+	if cfg.MemLimit > 0 {
+		memLimit = fmt.Sprintf("%dMiB", cfg.MemLimit)
+	}
+	if cfg.GCPercent >= 0 {
+		gogc = strconv.Itoa(cfg.GCPercent)
+	}
+	return []expfmt.Label{
+		{Name: "task", Value: task},
+		{Name: "config", Value: cfg.Name},
+		{Name: "gomaxprocs", Value: maxProcs},
+		{Name: "gogc", Value: gogc},
+		{Name: "gomemlimit", Value: memLimit},
+		{Name: "repetition", Value: strconv.Itoa(repetition)},
+	}
+}
+
+// agentLabels are the labels of an agent metrics file, which does not
+// know its config. The runtime flags are those the agent ran with, as
+// read from runtime/metrics when it recorded them.
+func agentLabels(task, config string, repetition int, rm *agentmetrics.RuntimeMetrics) []expfmt.Label {
+	maxProcs, memLimit, gogc := "", "", ""
+	if rm != nil {
+		if n, ok := rm.Uint64["/sched/gomaxprocs:threads"]; ok {
+			maxProcs = strconv.FormatUint(n, 10)
+		}
+		if n, ok := rm.Uint64["/gc/gogc:percent"]; ok {
+			// GOGC=off reads as the uint64 conversion of -1
+			gogc = "off"
+			if n <= math.MaxInt32 {
+				gogc = strconv.FormatUint(n, 10)
+			}
+		}
+		if n, ok := rm.Uint64["/gc/gomemlimit:bytes"]; ok {
+			switch {
+			case n >= math.MaxInt64:
+				memLimit = "default"
+			case n%(1<<20) == 0:
+				memLimit = fmt.Sprintf("%dMiB", n>>20)
+			default:
+				memLimit = strconv.FormatUint(n, 10)
+			}
+		}
+	}
+	return []expfmt.Label{
+		{Name: "task", Value: task},
+		{Name: "config", Value: config},
+		{Name: "gomaxprocs", Value: maxProcs},
+		{Name: "gogc", Value: gogc},
+		{Name: "gomemlimit", Value: memLimit},
+		{Name: "repetition", Value: strconv.Itoa(repetition)},
+	}
+}
+
+// with returns labels plus one more, leaving labels untouched
+func with(labels []expfmt.Label, name, value string) []expfmt.Label {
+	return append(append([]expfmt.Label{}, labels...), expfmt.Label{Name: name, Value: value})
+}
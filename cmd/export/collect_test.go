@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/natalie/go-flags-eval/internal/runner"
+)
+
+func TestDistinctRepetitions(t *testing.T) {
+	run := func(task, config string, repetition int) runner.BenchmarkResult {
+		return runner.BenchmarkResult{Task: task, Config: runner.BenchmarkConfig{Name: config}, Repetition: repetition}
+	}
+	tests := []struct {
+		name    string
+		results []runner.BenchmarkResult
+		want    []int
+	}{
+		{
+			name:    "recorded",
+			results: []runner.BenchmarkResult{run("a", "default", 2), run("a", "default", 1), run("a", "gc-off", 1)},
+			want:    []int{2, 1, 1},
+		},
+		{
+			name:    "legacy",
+			results: []runner.BenchmarkResult{run("a", "default", 0), run("a", "gc-off", 0), run("a", "default", 0), run("b", "default", 0)},
+			want:    []int{1, 1, 2, 1},
+		},
+		{
+			// Only the pair with duplicates is renumbered
+			name:    "duplicates",
+			results: []runner.BenchmarkResult{run("a", "default", 3), run("a", "default", 3), run("b", "default", 3)},
+			want:    []int{1, 2, 3},
+		},
+	}
+	for _, tt := range tests {
+		if got := distinctRepetitions(tt.results); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: distinctRepetitions = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
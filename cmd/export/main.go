@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/natalie/go-flags-eval/internal/agentmetrics"
+	"github.com/natalie/go-flags-eval/internal/expfmt"
+	"github.com/natalie/go-flags-eval/internal/results"
+)
+
+var (
+	inputFile  = flag.String("input", "benchmark_results.json", "Input JSON file with benchmark results")
+	agentMode  = flag.Bool("agent", false, "Export the agent metrics files (-metrics-output) given as arguments instead of -input")
+	taskName   = flag.String("task", "", "With -agent: task label (default: the file name without extension)")
+	configName = flag.String("config", "default", "With -agent: config label")
+	format     = flag.String("format", "prometheus", "Output format: prometheus (text format 0.0.4) or openmetrics")
+	textfile   = flag.String("textfile", "", "Write the metrics to this .prom file for node_exporter's textfile collector")
+	serveAddr  = flag.String("serve", "", "Serve the metrics on /metrics at this address, e.g. localhost:9101")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: export [flags]\n       export -agent [flags] metrics.json...\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Exports benchmark results or agent metrics in the Prometheus text format\nor OpenMetrics, to stdout, a node_exporter textfile or a /metrics endpoint.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *agentMode && flag.NArg() == 0 {
+		log.Fatalf("-agent needs at least one agent metrics file")
+	}
+	if !*agentMode && flag.NArg() > 0 {
+		log.Fatalf("Unexpected arguments %v: results are read from -input, agent metrics files need -agent", flag.Args())
+	}
+	outFormat, err := expfmt.ParseFormat(*format)
+	if err != nil {
+		log.Fatalf("Invalid -format: %v", err)
+	}
+
+	families, err := collect()
+	if err != nil {
+		log.Fatalf("Failed to read metrics: %v", err)
+	}
+
+	if *textfile != "" {
+		// The textfile collector only reads the text format
+		if isFlagSet("format") && outFormat != expfmt.Text {
+			log.Fatalf("-textfile writes the Prometheus text format; node_exporter cannot read -format=%s", *format)
+		}
+		if err := writeTextfile(*textfile, families); err != nil {
+			log.Fatalf("Failed to write textfile: %v", err)
+		}
+		log.Printf("Metrics written to %s", *textfile)
+	}
+
+	if *serveAddr != "" {
+		serve(*serveAddr)
+		return
+	}
+
+	if *textfile == "" {
+		if err := expfmt.Write(os.Stdout, families, outFormat); err != nil {
+			log.Fatalf("Failed to write metrics: %v", err)
+		}
+	}
+}
+
+// collect reads the inputs and converts them into metric families
+func collect() ([]*expfmt.Family, error) {
+	fs := &expfmt.Families{}
+	if !*agentMode {
+		doc, err := results.Read(*inputFile)
+		if err != nil {
+			return nil, err
+		}
+		collectResults(fs, doc)
+		return fs.List(), nil
+	}
+
+	// Files of the same task count as its repetitions, in argument order
+	repetitions := map[string]int{}
+	for _, filename := range flag.Args() {
+		m, err := agentmetrics.ReadFromFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		task := *taskName
+		if task == "" {
+			task = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		}
+		repetitions[task]++
+		collectMetrics(fs, m, agentLabels(task, *configName, repetitions[task], m.Runtime))
+	}
+	return fs.List(), nil
+}
+
+// writeTextfile replaces filename atomically, so that node_exporter never
+// reads a partially written file
+func writeTextfile(filename string, families []*expfmt.Family) error {
+	if filepath.Ext(filename) != ".prom" {
+		return fmt.Errorf("%s: the textfile collector only reads files ending in .prom", filename)
+	}
+
+	var buf bytes.Buffer
+	if err := expfmt.Write(&buf, families, expfmt.Text); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".export-*.prom.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// serve exposes the metrics on /metrics, in OpenMetrics when the scraper
+// accepts it and in the text format otherwise
+func serve(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		// Inputs are read again for every scrape, so that results rewritten
+		// by a later benchmark run show up without a restart
+		families, err := collect()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var buf bytes.Buffer
+		f := expfmt.Negotiate(r.Header.Get("Accept"))
+		if err := expfmt.Write(&buf, families, f); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", f.ContentType())
+		w.Write(buf.Bytes())
+	})
+
+	log.Printf("Serving metrics on http://%s/metrics", addr)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
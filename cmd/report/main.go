@@ -1,31 +1,19 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"sort"
+	"strings"
 	"time"
-)
-
-type BenchmarkConfig struct {
-	Name      string
-	MaxProcs  int
-	MemLimit  int64
-	GCPercent int
-}
 
-type BenchmarkResult struct {
-	Config          BenchmarkConfig
-	Duration        time.Duration
-	MemoryAllocated uint64
-	NumGC           uint32
-	PauseTimeNs     uint64
-	ExitCode        int
-	Error           string
-}
+	"github.com/natalie/go-flags-eval/internal/gctrace"
+	"github.com/natalie/go-flags-eval/internal/results"
+	"github.com/natalie/go-flags-eval/internal/runner"
+	"github.com/natalie/go-flags-eval/internal/stats"
+)
 
 var (
 	inputFile  = flag.String("input", "benchmark_results.json", "Input JSON file with benchmark results")
@@ -35,19 +23,14 @@ var (
 func main() {
 	flag.Parse()
 
-	// Read results
-	data, err := os.ReadFile(*inputFile)
+	// Read results, migrating files of earlier schema versions
+	output, err := results.Read(*inputFile)
 	if err != nil {
 		log.Fatalf("Failed to read input file: %v", err)
 	}
 
-	var results []BenchmarkResult
-	if err := json.Unmarshal(data, &results); err != nil {
-		log.Fatalf("Failed to parse JSON: %v", err)
-	}
-
 	// Generate report
-	report := generateReport(results)
+	report := generateReport(output)
 
 	// Write report
 	if err := os.WriteFile(*outputFile, []byte(report), 0644); err != nil {
@@ -58,14 +41,18 @@ func main() {
 	fmt.Println("\n" + report)
 }
 
-func generateReport(results []BenchmarkResult) string {
+func generateReport(output results.Document) string {
+	results, summaries := output.Results, output.Summaries
+
 	report := "# Go Flags Benchmark Report\n\n"
 	report += fmt.Sprintf("Generated: %s\n\n", time.Now().Format(time.RFC1123))
 
 	// Add agent information
 	report += "## Agent Information\n\n"
-	report += fmt.Sprintf("- **Total Agents**: 4\n")
-	report += fmt.Sprintf("- **Total Benchmark Runs**: %d (4 agents × 13 configurations)\n\n", len(results))
+	taskNames, configNames := countDistinct(results)
+	report += fmt.Sprintf("- **Total Agents**: %d\n", taskNames)
+	report += fmt.Sprintf("- **Total Benchmark Runs**: %d of %d task/configuration pairs (%d agents, %d configurations)\n\n",
+		len(results), len(summaries), taskNames, configNames)
 	report += "### Active Agents\n\n"
 	report += "1. **Code Generator** - Generates Go source files with functions and types using concurrent workers\n"
 	report += "2. **File Searcher** - Searches codebase for patterns using concurrent workers (grep-like functionality)\n"
@@ -73,6 +60,21 @@ func generateReport(results []BenchmarkResult) string {
 	report += "4. **AST Parser** - Parses Go files and extracts abstract syntax tree information (memory-intensive)\n"
 	report += "\n"
 
+	if !output.Environment.Timestamp.IsZero() {
+		report += "### Environment\n\n"
+		report += generateEnvironment(output.Environment)
+		if output.Schedule.Order != "" {
+			report += generateSchedule(output.Schedule)
+		}
+		report += "\n"
+	}
+
+	if len(output.Builds) > 0 {
+		report += "### Agent Builds\n\n"
+		report += generateBuildsTable(output.Builds)
+		report += "\n"
+	}
+
 	// Add background section
 	report += "## Understanding Go Runtime Flags\n\n"
 	report += generateFlagsExplanation()
@@ -84,42 +86,194 @@ func generateReport(results []BenchmarkResult) string {
 	report += "\n"
 
 	// Group results by task
-	taskGroups := groupByTask(results)
+	taskGroups := groupByTask(results, summaries)
 
 	// Overall summary
 	report += "## Executive Summary\n\n"
-	report += generateSummary(results)
+	report += generateSummary(results, summaries)
 	report += "\n"
 
+	// Repeated-run statistics
+	if hasRepetitions(summaries) {
+		report += "## Statistical Summary\n\n"
+		report += generateStatisticsTable(summaries)
+		report += "\n"
+	}
+
 	// Detailed results by task
-	for taskName, taskResults := range taskGroups {
-		report += fmt.Sprintf("## Task: %s\n\n", taskName)
-		report += generateTaskAnalysis(taskResults)
+	for _, group := range taskGroups {
+		report += fmt.Sprintf("## Task: %s\n\n", group.name)
+		if group.description != "" {
+			report += fmt.Sprintf("%s\n\n", group.description)
+		}
+		report += generateTaskAnalysis(group.summaries)
+		report += "\n"
+	}
+
+	// Go benchmark tasks, by benchmark and config
+	if hasGoBenchmarks(summaries) {
+		report += "## Go Benchmarks\n\n"
+		report += generateGoBenchmarksTable(summaries)
+		report += "\n"
+	}
+
+	// Agents looping their workload with -duration
+	if hasSteadyState(summaries) {
+		report += "## Steady State\n\n"
+		report += generateSteadyStateTable(summaries)
+		report += "\n"
+	}
+
+	// GC cost from the agents' runtime/metrics
+	if hasRuntimeMetrics(summaries) {
+		report += "## GC Cost\n\n"
+		report += generateRuntimeTable(summaries)
+		report += "\n"
+	}
+
+	// Heap against the GC's goal over time, from the agents' samplers
+	if hasTimeline(results) {
+		report += "## Heap Timeline\n\n"
+		report += generateTimelineSection(results)
+	}
+
+	// Groups of agents sharing the machine
+	if hasCoTenancy(summaries) {
+		report += "## Co-Tenancy\n\n"
+		report += generateCoTenancyTable(summaries)
+		report += "\n"
+	}
+
+	// Cgroup counters, when runs were placed in cgroup leaves
+	if hasCgroupStats(results) {
+		report += "## Container Limits\n\n"
+		report += generateCgroupTable(results)
+		report += "\n"
+	}
+
+	// Failures grouped by class
+	if failures := groupFailures(results); len(failures) > 0 {
+		report += "## Failures\n\n"
+		report += generateFailuresSection(failures)
+		report += "\n"
+	}
+
+	// GC traces, when the run was taken with -gctrace
+	if hasGCTrace(results) {
+		report += "## GC Trace Analysis\n\n"
+		report += generateGCTraceTable(results)
 		report += "\n"
 	}
 
 	// Recommendations
 	report += "## Recommendations\n\n"
-	report += generateRecommendations(results)
+	report += generateRecommendations(summaries)
 	report += "\n"
 
 	// Raw data table
 	report += "## Complete Results by Scenario\n\n"
-	report += generateDataTable(results)
+	report += generateDataTable(summaries)
 	report += "\n"
 
 	return report
 }
 
-func groupByTask(results []BenchmarkResult) map[string][]BenchmarkResult {
-	// For simplicity, group all results together
-	// In a real implementation, would parse task info from results
-	return map[string][]BenchmarkResult{
-		"All Tasks": results,
+func generateBuildsTable(builds []runner.AgentBuild) string {
+	table := "Agents are compiled once before benchmarking; build time is excluded from all measured durations.\n\n"
+	table += "| Task | Package | Source Hash | Build Time |\n"
+	table += "|------|---------|-------------|------------|\n"
+	for _, b := range builds {
+		buildTime := b.BuildTime.Round(time.Millisecond).String()
+		if b.Cached {
+			buildTime = "cached"
+		}
+		table += fmt.Sprintf("| %s | `%s` | `%.12s` | %s |\n", b.Task, b.Package, b.SourceHash, buildTime)
 	}
+	return table
+}
+
+type taskGroup struct {
+	name        string
+	description string
+	results     []runner.BenchmarkResult
+	summaries   []runner.BenchmarkSummary
 }
 
-func generateSummary(results []BenchmarkResult) string {
+// groupByTask groups results and per-pair summaries by task in the order
+// tasks first appear in the results. Results from files written before tasks
+// were recorded all fall into a single "All Tasks" group.
+func groupByTask(results []runner.BenchmarkResult, summaries []runner.BenchmarkSummary) []taskGroup {
+	groupName := func(task string) string {
+		if task == "" {
+			return "All Tasks"
+		}
+		return task
+	}
+	groups := []taskGroup{}
+	index := map[string]int{}
+	group := func(task, description string) *taskGroup {
+		name := groupName(task)
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, taskGroup{name: name, description: description})
+		}
+		return &groups[i]
+	}
+	for _, r := range results {
+		g := group(r.Task, r.TaskDescription)
+		g.results = append(g.results, r)
+	}
+	for _, s := range summaries {
+		g := group(s.Task, "")
+		g.summaries = append(g.summaries, s)
+	}
+	return groups
+}
+
+func countDistinct(results []runner.BenchmarkResult) (tasks, configs int) {
+	taskSet := map[string]bool{}
+	configSet := map[string]bool{}
+	for _, r := range results {
+		taskSet[r.Task] = true
+		configSet[r.Config.Name] = true
+	}
+	return len(taskSet), len(configSet)
+}
+
+func generateEnvironment(env runner.Environment) string {
+	orUnknown := func(s string) string {
+		if s == "" {
+			return "unknown"
+		}
+		return s
+	}
+
+	commit := orUnknown(env.GitCommit)
+	if env.GitDirty {
+		commit += " (dirty)"
+	}
+
+	out := fmt.Sprintf("- **Timestamp**: %s\n", env.Timestamp.Format(time.RFC1123))
+	out += fmt.Sprintf("- **Host**: %s\n", orUnknown(env.Hostname))
+	out += fmt.Sprintf("- **Platform**: %s/%s, kernel %s\n", env.GOOS, env.GOARCH, orUnknown(env.Kernel))
+	out += fmt.Sprintf("- **CPU**: %s (%d logical CPUs)\n", orUnknown(env.CPUModel), env.NumCPU)
+	out += fmt.Sprintf("- **Go**: %s (runner built with %s)\n", orUnknown(env.GoVersion), env.RunnerGo)
+	out += fmt.Sprintf("- **Cgroup limits**: memory %s, cpu %s\n", orUnknown(env.CgroupMemory), orUnknown(env.CgroupCPU))
+	out += fmt.Sprintf("- **Git commit**: %s\n", commit)
+	return out
+}
+
+func generateSchedule(schedule runner.Schedule) string {
+	order := string(schedule.Order)
+	if schedule.Seed != 0 {
+		order += fmt.Sprintf(" (seed %d)", schedule.Seed)
+	}
+	return fmt.Sprintf("- **Run order**: %s, %d discarded warmup run(s) per task/config pair\n", order, schedule.Warmup)
+}
+
+func generateSummary(results []runner.BenchmarkResult, summaries []runner.BenchmarkSummary) string {
 	if len(results) == 0 {
 		return "No results available.\n"
 	}
@@ -138,7 +292,8 @@ func generateSummary(results []BenchmarkResult) string {
 		}
 	}
 
-	summary := fmt.Sprintf("- **Total Configurations Tested**: %d\n", len(results))
+	summary := fmt.Sprintf("- **Task/Configuration Pairs Tested**: %d\n", len(summaries))
+	summary += fmt.Sprintf("- **Measured Runs**: %d\n", len(results))
 	summary += fmt.Sprintf("- **Successful Runs**: %d\n", successCount)
 	summary += fmt.Sprintf("- **Failed Runs**: %d\n", len(results)-successCount)
 
@@ -151,173 +306,476 @@ func generateSummary(results []BenchmarkResult) string {
 	return summary
 }
 
-func generateTaskAnalysis(results []BenchmarkResult) string {
-	analysis := "### Performance Analysis\n\n"
+func hasRepetitions(summaries []runner.BenchmarkSummary) bool {
+	for _, s := range summaries {
+		if s.Runs > 1 {
+			return true
+		}
+	}
+	return false
+}
 
-	// Find best configurations
-	successResults := []BenchmarkResult{}
-	for _, r := range results {
-		if r.Error == "" {
-			successResults = append(successResults, r)
+func generateStatisticsTable(summaries []runner.BenchmarkSummary) string {
+	table := "Mean values across repetitions with 95% confidence intervals.\n\n"
+	table += "| Task | Configuration | Runs | Duration (mean ± CI) | Median | Min | Max | Memory (MB, mean ± CI) | Peak RSS (MB, mean ± CI) | GC Runs | GC Pause |\n"
+	table += "|------|---------------|------|----------------------|--------|-----|-----|------------------------|--------------------------|---------|----------|\n"
+
+	for _, s := range summaries {
+		if s.Duration.N == 0 {
+			table += fmt.Sprintf("| %s | %s | %d | failed | - | - | - | - | - | - | - |\n", s.Task, s.Config.Name, s.Runs)
+			continue
+		}
+		runs := fmt.Sprintf("%d", s.Duration.N)
+		if s.Failures > 0 {
+			runs = fmt.Sprintf("%d (%d failed)", s.Duration.N, s.Failures)
 		}
+		table += fmt.Sprintf("| %s | %s | %s | %v ± %v | %v | %v | %v | %.2f ± %.2f | %.2f ± %.2f | %.1f ± %.1f | %v |\n",
+			s.Task,
+			s.Config.Name,
+			runs,
+			time.Duration(s.Duration.Mean).Round(time.Microsecond),
+			time.Duration(s.Duration.CIHigh-s.Duration.Mean).Round(time.Microsecond),
+			time.Duration(s.Duration.Median).Round(time.Microsecond),
+			time.Duration(s.Duration.Min).Round(time.Microsecond),
+			time.Duration(s.Duration.Max).Round(time.Microsecond),
+			s.MemoryAllocated.Mean/(1024*1024),
+			(s.MemoryAllocated.CIHigh-s.MemoryAllocated.Mean)/(1024*1024),
+			s.PeakRSS.Mean/(1024*1024),
+			(s.PeakRSS.CIHigh-s.PeakRSS.Mean)/(1024*1024),
+			s.NumGC.Mean,
+			s.NumGC.CIHigh-s.NumGC.Mean,
+			time.Duration(s.PauseTimeNs.Mean).Round(time.Microsecond))
 	}
 
-	if len(successResults) == 0 {
-		return "No successful runs for this task.\n"
+	return table
+}
+
+func hasGoBenchmarks(summaries []runner.BenchmarkSummary) bool {
+	for _, s := range summaries {
+		if len(s.Benchmarks) > 0 {
+			return true
+		}
 	}
+	return false
+}
 
-	// Sort by duration (fastest to slowest)
-	sorted := make([]BenchmarkResult, len(successResults))
-	copy(sorted, successResults)
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].Duration < sorted[j].Duration
-	})
+func generateGoBenchmarksTable(summaries []runner.BenchmarkSummary) string {
+	table := "Parsed from `go test -bench` output, pooled over `-test.count` lines and repetitions. Custom metrics reported with `b.ReportMetric` are listed by unit.\n\n"
+	table += "| Task | Benchmark | Configuration | Samples | ns/op (mean ± CI) | B/op | allocs/op | Custom Metrics |\n"
+	table += "|------|-----------|---------------|---------|-------------------|------|-----------|----------------|\n"
+
+	for _, s := range summaries {
+		for _, b := range s.Benchmarks {
+			units := make([]string, 0, len(b.Metrics))
+			for unit := range b.Metrics {
+				units = append(units, unit)
+			}
+			sort.Strings(units)
+			custom := []string{}
+			for _, unit := range units {
+				custom = append(custom, fmt.Sprintf("%.4g %s", b.Metrics[unit].Mean, unit))
+			}
+			if len(custom) == 0 {
+				custom = append(custom, "-")
+			}
+
+			table += fmt.Sprintf("| %s | %s | %s | %d | %.1f ± %.1f | %.0f | %.1f | %s |\n",
+				s.Task,
+				b.Name,
+				s.Config.Name,
+				b.NsPerOp.N,
+				b.NsPerOp.Mean,
+				b.NsPerOp.CIHigh-b.NsPerOp.Mean,
+				b.BytesPerOp.Mean,
+				b.AllocsPerOp.Mean,
+				strings.Join(custom, ", "))
+		}
+	}
 
-	analysis += "#### Best 4 Fastest Configurations\n\n"
-	analysis += "| Rank | Configuration | Duration | Memory (MB) | GC Runs |\n"
-	analysis += "|------|---------------|----------|-------------|----------|\n"
-	for i := 0; i < min(4, len(sorted)); i++ {
-		r := sorted[i]
-		analysis += fmt.Sprintf("| %d | %s | %v | %.2f | %d |\n",
-			i+1,
-			r.Config.Name,
-			r.Duration,
-			float64(r.MemoryAllocated)/(1024*1024),
-			r.NumGC)
-	}
-	analysis += "\n"
-
-	analysis += "#### Worst 4 Slowest Configurations\n\n"
-	analysis += "| Rank | Configuration | Duration | Memory (MB) | GC Runs |\n"
-	analysis += "|------|---------------|----------|-------------|----------|\n"
-	start := max(0, len(sorted)-4)
-	for i := len(sorted) - 1; i >= start; i-- {
-		r := sorted[i]
-		analysis += fmt.Sprintf("| %d | %s | %v | %.2f | %d |\n",
-			len(sorted)-i,
-			r.Config.Name,
-			r.Duration,
-			float64(r.MemoryAllocated)/(1024*1024),
-			r.NumGC)
+	return table
+}
+
+func hasSteadyState(summaries []runner.BenchmarkSummary) bool {
+	for _, s := range summaries {
+		if s.SteadyState != nil {
+			return true
+		}
 	}
-	analysis += "\n"
+	return false
+}
 
-	// Sort by memory (lowest to highest)
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].MemoryAllocated < sorted[j].MemoryAllocated
-	})
+func generateSteadyStateTable(summaries []runner.BenchmarkSummary) string {
+	table := "Agents that looped their workload for a fixed time after a warmup. Throughput is workload iterations per second of the measured window, the percentiles are of single iterations, and memory and GC figures elsewhere in this report cover the window only.\n\n"
+	table += "| Task | Configuration | Runs | Throughput (ops/s) | p50 | p90 | p99 | p999 | GC Runs (mean) |\n"
+	table += "|------|---------------|------|--------------------|-----|-----|-----|------|----------------|\n"
 
-	analysis += "#### Best 4 Lowest Memory Usage\n\n"
-	analysis += "| Rank | Configuration | Memory (MB) | Duration | GC Runs |\n"
-	analysis += "|------|---------------|-------------|----------|----------|\n"
-	for i := 0; i < min(4, len(sorted)); i++ {
-		r := sorted[i]
-		analysis += fmt.Sprintf("| %d | %s | %.2f | %v | %d |\n",
-			i+1,
-			r.Config.Name,
-			float64(r.MemoryAllocated)/(1024*1024),
-			r.Duration,
-			r.NumGC)
-	}
-	analysis += "\n"
-
-	analysis += "#### Worst 4 Highest Memory Usage\n\n"
-	analysis += "| Rank | Configuration | Memory (MB) | Duration | GC Runs |\n"
-	analysis += "|------|---------------|-------------|----------|----------|\n"
-	start = max(0, len(sorted)-4)
-	for i := len(sorted) - 1; i >= start; i-- {
-		r := sorted[i]
-		analysis += fmt.Sprintf("| %d | %s | %.2f | %v | %d |\n",
-			len(sorted)-i,
+	for _, s := range summaries {
+		ss := s.SteadyState
+		if ss == nil {
+			continue
+		}
+		table += fmt.Sprintf("| %s | %s | %d | %.2f ± %.2f | %v | %v | %v | %v | %.1f |\n",
+			s.Task,
+			s.Config.Name,
+			ss.OpsPerSec.N,
+			ss.OpsPerSec.Mean,
+			ss.OpsPerSec.CIHigh-ss.OpsPerSec.Mean,
+			time.Duration(ss.LatencyP50.Mean).Round(time.Microsecond),
+			time.Duration(ss.LatencyP90.Mean).Round(time.Microsecond),
+			time.Duration(ss.LatencyP99.Mean).Round(time.Microsecond),
+			time.Duration(ss.LatencyP999.Mean).Round(time.Microsecond),
+			s.NumGC.Mean)
+	}
+
+	return table
+}
+
+func hasRuntimeMetrics(summaries []runner.BenchmarkSummary) bool {
+	for _, s := range summaries {
+		if s.Runtime != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func generateRuntimeTable(summaries []runner.BenchmarkSummary) string {
+	table := "From the `runtime/metrics` each agent read at exit. GC CPU is the GC's share of GOMAXPROCS × wall time; a high share under a GOMEMLIMIT config means the limit is too tight for the live heap. The GC CPU limiter caps that share at 50% and kicks in only when the limit forces back-to-back cycles, so any run counted under Limiter is a sign of memory pressure. Pause and scheduling latency percentiles are bucket upper bounds.\n\n"
+	table += "| Task | Configuration | Runs | GC CPU | Heap Goal (MB) | GC Pause p99 | Sched Latency p99 | Limiter |\n"
+	table += "|------|---------------|------|--------|----------------|--------------|-------------------|---------|\n"
+
+	for _, s := range summaries {
+		rt := s.Runtime
+		if rt == nil {
+			continue
+		}
+		limiter := "-"
+		if rt.LimiterRuns > 0 {
+			limiter = fmt.Sprintf("%d/%d", rt.LimiterRuns, rt.GCCPUFraction.N)
+		}
+		table += fmt.Sprintf("| %s | %s | %d | %.1f%% | %.2f | %v | %v | %s |\n",
+			s.Task,
+			s.Config.Name,
+			rt.GCCPUFraction.N,
+			rt.GCCPUFraction.Mean*100,
+			rt.HeapGoal.Mean/(1024*1024),
+			time.Duration(rt.GCPauseP99.Mean).Round(time.Microsecond),
+			time.Duration(rt.SchedLatencyP99.Mean).Round(time.Microsecond),
+			limiter)
+	}
+
+	return table
+}
+
+func hasCoTenancy(summaries []runner.BenchmarkSummary) bool {
+	for _, s := range summaries {
+		if s.CoTenancy != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func generateCoTenancyTable(summaries []runner.BenchmarkSummary) string {
+	table := "Groups of agent processes started together under one configuration. Makespan runs from the common start until the last process exited; throughput is successful processes per second of makespan, and the percentiles are of the processes' own durations. GOMAXPROCS applies to each process, so its sum across the group can oversubscribe the CPUs.\n\n"
+	table += "| Group | Configuration | Processes | Runs | Makespan (mean) | Throughput (proc/s) | p50 | p99 | Max | Start Skew |\n"
+	table += "|-------|---------------|-----------|------|-----------------|---------------------|-----|-----|-----|------------|\n"
+
+	for _, s := range summaries {
+		co := s.CoTenancy
+		if co == nil {
+			continue
+		}
+		table += fmt.Sprintf("| %s | %s | %d | %d | %v | %.2f ± %.2f | %v | %v | %v | %v |\n",
+			s.Task,
+			s.Config.Name,
+			co.Processes,
+			s.Duration.N,
+			time.Duration(s.Duration.Mean).Round(time.Millisecond),
+			co.Throughput.Mean,
+			co.Throughput.CIHigh-co.Throughput.Mean,
+			time.Duration(co.DurationP50.Mean).Round(time.Millisecond),
+			time.Duration(co.DurationP99.Mean).Round(time.Millisecond),
+			time.Duration(co.DurationMax.Mean).Round(time.Millisecond),
+			time.Duration(co.StartSkew.Mean).Round(time.Microsecond))
+	}
+
+	return table
+}
+
+func hasCgroupStats(results []runner.BenchmarkResult) bool {
+	for _, r := range results {
+		if r.Cgroup != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func generateCgroupTable(results []runner.BenchmarkResult) string {
+	table := "Each run was placed in a transient cgroup v2 leaf. `high` counts how often usage exceeded memory.high and was reclaimed, `max` how often it hit memory.max.\n\n"
+	table += "| Task | Configuration | Run | memory.max | memory.high | cpu.max | Peak (MB) | high | max | OOM Kills | Throttled Periods | Throttled Time |\n"
+	table += "|------|---------------|-----|------------|-------------|---------|-----------|------|-----|-----------|-------------------|----------------|\n"
+
+	for _, r := range results {
+		c := r.Cgroup
+		if c == nil {
+			continue
+		}
+		throttled := "-"
+		if c.CPUNrPeriods > 0 {
+			throttled = fmt.Sprintf("%d/%d", c.CPUNrThrottled, c.CPUNrPeriods)
+		}
+		table += fmt.Sprintf("| %s | %s | %d | %s | %s | %s | %.2f | %d | %d | %d | %s | %v |\n",
+			r.Task,
 			r.Config.Name,
-			float64(r.MemoryAllocated)/(1024*1024),
-			r.Duration,
-			r.NumGC)
+			r.Repetition,
+			c.MemoryMax,
+			c.MemoryHigh,
+			c.CPUMax,
+			float64(c.MemoryPeak)/(1024*1024),
+			c.MemoryEventsHigh,
+			c.MemoryEventsMax,
+			c.MemoryEventsOOMKill,
+			throttled,
+			(time.Duration(c.CPUThrottledUsec) * time.Microsecond).Round(time.Millisecond))
 	}
-	analysis += "\n"
 
-	// Sort by GC runs (fewest to most)
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].NumGC < sorted[j].NumGC
+	return table
+}
+
+type failureGroup struct {
+	class   string
+	results []runner.BenchmarkResult
+}
+
+// groupFailures groups failed runs by failure class, most frequent first.
+// Results from files written before failures were classified are grouped
+// as "unclassified".
+func groupFailures(results []runner.BenchmarkResult) []failureGroup {
+	index := map[string]int{}
+	groups := []failureGroup{}
+	for _, r := range results {
+		if r.Error == "" {
+			continue
+		}
+		class := "unclassified"
+		if r.Failure != nil {
+			class = string(r.Failure.Class)
+		}
+		i, ok := index[class]
+		if !ok {
+			i = len(groups)
+			index[class] = i
+			groups = append(groups, failureGroup{class: class})
+		}
+		groups[i].results = append(groups[i].results, r)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].results) > len(groups[j].results)
 	})
+	return groups
+}
 
-	analysis += "#### Best 4 Fewest GC Runs\n\n"
-	analysis += "| Rank | Configuration | GC Runs | Duration | Memory (MB) |\n"
-	analysis += "|------|---------------|---------|----------|-------------|\n"
-	for i := 0; i < min(4, len(sorted)); i++ {
-		r := sorted[i]
-		analysis += fmt.Sprintf("| %d | %s | %d | %v | %.2f |\n",
-			i+1,
-			r.Config.Name,
-			r.NumGC,
-			r.Duration,
-			float64(r.MemoryAllocated)/(1024*1024))
-	}
-	analysis += "\n"
-
-	analysis += "#### Worst 4 Most GC Runs\n\n"
-	analysis += "| Rank | Configuration | GC Runs | Duration | Memory (MB) |\n"
-	analysis += "|------|---------------|---------|----------|-------------|\n"
-	start = max(0, len(sorted)-4)
-	for i := len(sorted) - 1; i >= start; i-- {
-		r := sorted[i]
-		analysis += fmt.Sprintf("| %d | %s | %d | %v | %.2f |\n",
-			len(sorted)-i,
+func generateFailuresSection(groups []failureGroup) string {
+	section := "| Class | Runs |\n"
+	section += "|-------|------|\n"
+	for _, g := range groups {
+		section += fmt.Sprintf("| %s | %d |\n", g.class, len(g.results))
+	}
+	section += "\n"
+
+	for _, g := range groups {
+		section += fmt.Sprintf("### %s\n\n", g.class)
+		section += "| Task | Configuration | Run | Signal | Exit Code | Duration | Message |\n"
+		section += "|------|---------------|-----|--------|-----------|----------|---------|\n"
+		for _, r := range g.results {
+			signal := "-"
+			if r.Failure != nil && r.Failure.Signal != "" {
+				signal = r.Failure.Signal
+			}
+			section += fmt.Sprintf("| %s | %s | %d | %s | %d | %v | %s |\n",
+				r.Task,
+				r.Config.Name,
+				r.Repetition,
+				signal,
+				r.ExitCode,
+				r.Duration.Round(time.Millisecond),
+				strings.ReplaceAll(r.Error, "|", "\\|"))
+		}
+		section += "\n"
+	}
+
+	return section
+}
+
+func hasGCTrace(results []runner.BenchmarkResult) bool {
+	for _, r := range results {
+		if len(r.GCTrace) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func generateGCTraceTable(results []runner.BenchmarkResult) string {
+	table := "Parsed from `GODEBUG=gctrace=1`. Many limit-triggered cycles with a high GC CPU share indicate that GOMEMLIMIT is forcing the collector to run continuously (a GC death spiral).\n\n"
+	table += "| Task | Configuration | Run | GC Cycles | Forced | Limit-Triggered | Total STW | Max STW | Peak Heap (MB) | Peak Goal (MB) | GC CPU |\n"
+	table += "|------|---------------|-----|-----------|--------|-----------------|-----------|---------|----------------|----------------|--------|\n"
+
+	for _, r := range results {
+		if r.Error != "" {
+			continue
+		}
+		s := gctrace.Summarize(r.GCTrace)
+		table += fmt.Sprintf("| %s | %s | %d | %d | %d | %d | %v | %v | %d | %d | %.0f%% |\n",
+			r.Task,
 			r.Config.Name,
-			r.NumGC,
-			r.Duration,
-			float64(r.MemoryAllocated)/(1024*1024))
+			r.Repetition,
+			s.Cycles,
+			s.Forced,
+			s.LimitTriggered,
+			s.TotalSTW.Round(time.Microsecond),
+			s.MaxSTW.Round(time.Microsecond),
+			s.PeakHeapBefore>>20,
+			s.PeakHeapGoal>>20,
+			s.FinalCPU)
+	}
+
+	return table
+}
+
+// generateTaskAnalysis ranks the configurations of a task by their means
+// over the successful runs, so that a single noisy run cannot decide a
+// ranking and every configuration appears once
+func generateTaskAnalysis(summaries []runner.BenchmarkSummary) string {
+	ranked := []runner.BenchmarkSummary{}
+	for _, s := range summaries {
+		if s.Duration.N > 0 {
+			ranked = append(ranked, s)
+		}
+	}
+	if len(ranked) == 0 {
+		return "No successful runs for this task.\n"
+	}
+
+	analysis := "### Performance Analysis\n\n"
+	analysis += "Configurations ranked by their mean over the successful runs, with 95% confidence intervals.\n\n"
+	rankings := []struct {
+		best, worst string
+		mean        func(s runner.BenchmarkSummary) float64
+	}{
+		{"Best 4 Fastest Configurations", "Worst 4 Slowest Configurations", func(s runner.BenchmarkSummary) float64 { return s.Duration.Mean }},
+		{"Best 4 Lowest Memory Usage", "Worst 4 Highest Memory Usage", func(s runner.BenchmarkSummary) float64 { return s.MemoryAllocated.Mean }},
+		{"Best 4 Fewest GC Runs", "Worst 4 Most GC Runs", func(s runner.BenchmarkSummary) float64 { return s.NumGC.Mean }},
+	}
+	for _, r := range rankings {
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return r.mean(ranked[i]) < r.mean(ranked[j])
+		})
+		analysis += rankingTable(r.best, ranked[:min(4, len(ranked))])
+
+		worst := []runner.BenchmarkSummary{}
+		for i := len(ranked) - 1; i >= max(0, len(ranked)-4); i-- {
+			worst = append(worst, ranked[i])
+		}
+		analysis += rankingTable(r.worst, worst)
 	}
-	analysis += "\n"
 
 	return analysis
 }
 
-func generateRecommendations(results []BenchmarkResult) string {
+// rankingTable lists summaries in the given order
+func rankingTable(title string, summaries []runner.BenchmarkSummary) string {
+	table := fmt.Sprintf("#### %s\n\n", title)
+	table += "| Rank | Configuration | Runs | Duration | Memory (MB) | GC Runs |\n"
+	table += "|------|---------------|------|----------|-------------|---------|\n"
+	for i, s := range summaries {
+		table += fmt.Sprintf("| %d | %s | %d | %s | %s | %.1f ± %.1f |\n",
+			i+1,
+			s.Config.Name,
+			s.Duration.N,
+			formatDuration(s.Duration),
+			formatMB(s.MemoryAllocated),
+			s.NumGC.Mean,
+			s.NumGC.CIHigh-s.NumGC.Mean)
+	}
+	return table + "\n"
+}
+
+// formatDuration formats a summary of nanoseconds as the mean ± the half
+// width of its 95% confidence interval
+func formatDuration(s stats.Summary) string {
+	return fmt.Sprintf("%v ± %v",
+		time.Duration(s.Mean).Round(time.Microsecond),
+		time.Duration(s.CIHigh-s.Mean).Round(time.Microsecond))
+}
+
+// formatMB formats a summary of bytes in MB as the mean ± the half width of
+// its 95% confidence interval
+func formatMB(s stats.Summary) string {
+	return fmt.Sprintf("%.2f ± %.2f", s.Mean/(1024*1024), (s.CIHigh-s.Mean)/(1024*1024))
+}
+
+func generateRecommendations(summaries []runner.BenchmarkSummary) string {
 	rec := "Based on the benchmark results:\n\n"
 
 	// Analyze GOMAXPROCS impact
 	rec += "### GOMAXPROCS\n\n"
-	rec += analyzeGOMAXPROCS(results)
+	rec += analyzeGOMAXPROCS(summaries)
 
 	// Analyze GOMEMLIMIT impact
 	rec += "\n### GOMEMLIMIT\n\n"
-	rec += analyzeGOMEMLIMIT(results)
+	rec += analyzeGOMEMLIMIT(summaries)
 
 	// Analyze GOGC impact
 	rec += "\n### GOGC\n\n"
-	rec += analyzeGOGC(results)
+	rec += analyzeGOGC(summaries)
 
 	return rec
 }
 
-func analyzeGOMAXPROCS(results []BenchmarkResult) string {
+func analyzeGOMAXPROCS(summaries []runner.BenchmarkSummary) string {
 	analysis := ""
 
-	// Find results with different GOMAXPROCS settings
-	maxProcsResults := filterByPrefix(results, "maxprocs-")
+	// Find configurations with different GOMAXPROCS settings
+	maxProcsSummaries := filterByPrefix(summaries, "maxprocs-")
 
-	if len(maxProcsResults) == 0 {
+	if len(maxProcsSummaries) == 0 {
 		return "Insufficient data to analyze GOMAXPROCS impact.\n"
 	}
 
-	// Sort by duration
-	sort.Slice(maxProcsResults, func(i, j int) bool {
-		return maxProcsResults[i].Duration < maxProcsResults[j].Duration
-	})
-
-	best := maxProcsResults[0]
-	analysis += fmt.Sprintf("- **Optimal value**: GOMAXPROCS=%d (Duration: %v)\n", best.Config.MaxProcs, best.Duration)
+	// The fastest setting of each task by mean duration; durations of
+	// different tasks are not comparable
+	best := []runner.BenchmarkSummary{}
+	index := map[string]int{}
+	for _, s := range maxProcsSummaries {
+		i, ok := index[s.Task]
+		if !ok {
+			index[s.Task] = len(best)
+			best = append(best, s)
+		} else if s.Duration.Mean < best[i].Duration.Mean {
+			best[i] = s
+		}
+	}
+	for _, s := range best {
+		task := s.Task
+		if task == "" {
+			task = "all tasks"
+		}
+		analysis += fmt.Sprintf("- **Optimal value for %s**: GOMAXPROCS=%d (Duration: %s over %d runs)\n",
+			task, s.Config.MaxProcs, formatDuration(s.Duration), s.Duration.N)
+	}
 	analysis += "- Increasing GOMAXPROCS generally improves performance for CPU-bound tasks\n"
 	analysis += "- Diminishing returns observed beyond 4 cores for most workloads\n"
 
 	return analysis
 }
 
-func analyzeGOMEMLIMIT(results []BenchmarkResult) string {
-	memLimitResults := filterByPrefix(results, "memlimit-")
+func analyzeGOMEMLIMIT(summaries []runner.BenchmarkSummary) string {
+	memLimitSummaries := filterByPrefix(summaries, "memlimit-")
 
-	if len(memLimitResults) == 0 {
+	if len(memLimitSummaries) == 0 {
 		return "Insufficient data to analyze GOMEMLIMIT impact.\n"
 	}
 
@@ -328,10 +786,10 @@ func analyzeGOMEMLIMIT(results []BenchmarkResult) string {
 	return analysis
 }
 
-func analyzeGOGC(results []BenchmarkResult) string {
-	gcResults := filterByPrefix(results, "gc-")
+func analyzeGOGC(summaries []runner.BenchmarkSummary) string {
+	gcSummaries := filterByPrefix(summaries, "gc-")
 
-	if len(gcResults) == 0 {
+	if len(gcSummaries) == 0 {
 		return "Insufficient data to analyze GOGC impact.\n"
 	}
 
@@ -342,59 +800,65 @@ func analyzeGOGC(results []BenchmarkResult) string {
 	return analysis
 }
 
-func generateDataTable(results []BenchmarkResult) string {
-	table := "| Scenario | Configuration | GOMAXPROCS | GOMEMLIMIT | GOGC | Duration | Memory (MB) | GC Runs | Status |\n"
-	table += "|----------|---------------|------------|------------|------|----------|-------------|---------|--------|\n"
-
-	// Group by sets of 13 (one complete run through all configs)
-	scenarios := []string{"Code Generation", "File Searching", "Code Refactoring", "AST Parsing"}
-	scenarioIdx := 0
-	configCount := 0
-
-	for _, r := range results {
-		// Determine scenario based on position in results
-		if configCount > 0 && configCount%13 == 0 {
-			scenarioIdx++
-		}
-		scenario := scenarios[scenarioIdx%len(scenarios)]
-
-		status := "✓"
-		if r.Error != "" {
-			status = "✗"
+// generateDataTable lists every task/configuration pair with its means
+// over the successful runs
+func generateDataTable(summaries []runner.BenchmarkSummary) string {
+	table := "Means over the successful runs of each pair with 95% confidence intervals.\n\n"
+	table += "| Scenario | Configuration | GOMAXPROCS | GOMEMLIMIT | GOGC | Runs | Duration | Memory (MB) | Peak RSS (MB) | CPU (user+sys) | GC Runs | Status |\n"
+	table += "|----------|---------------|------------|------------|------|------|----------|-------------|---------------|----------------|---------|--------|\n"
+
+	for _, s := range summaries {
+		scenario := s.Task
+		if scenario == "" {
+			scenario = "-"
 		}
 
 		memLimit := "-"
-		if r.Config.MemLimit > 0 {
-			memLimit = fmt.Sprintf("%dMB", r.Config.MemLimit)
+		if s.Config.MemLimit > 0 {
+			memLimit = fmt.Sprintf("%dMB", s.Config.MemLimit)
 		}
 
 		maxProcs := "default"
-		if r.Config.MaxProcs > 0 {
-			maxProcs = fmt.Sprintf("%d", r.Config.MaxProcs)
+		if s.Config.MaxProcs > 0 {
+			maxProcs = fmt.Sprintf("%d", s.Config.MaxProcs)
+		}
+
+		if s.Duration.N == 0 {
+			table += fmt.Sprintf("| %s | %s | %s | %s | %d | %d | - | - | - | - | - | ✗ |\n",
+				scenario, s.Config.Name, maxProcs, memLimit, s.Config.GCPercent, s.Runs)
+			continue
+		}
+		status := "✓"
+		if s.Failures > 0 {
+			status = fmt.Sprintf("%d of %d failed", s.Failures, s.Runs)
 		}
 
-		table += fmt.Sprintf("| %s | %s | %s | %s | %d | %v | %.2f | %d | %s |\n",
+		table += fmt.Sprintf("| %s | %s | %s | %s | %d | %d | %s | %s | %s | %v | %.1f ± %.1f | %s |\n",
 			scenario,
-			r.Config.Name,
+			s.Config.Name,
 			maxProcs,
 			memLimit,
-			r.Config.GCPercent,
-			r.Duration,
-			float64(r.MemoryAllocated)/(1024*1024),
-			r.NumGC,
+			s.Config.GCPercent,
+			s.Runs,
+			formatDuration(s.Duration),
+			formatMB(s.MemoryAllocated),
+			formatMB(s.PeakRSS),
+			time.Duration(s.CPUTime.Mean).Round(time.Millisecond),
+			s.NumGC.Mean,
+			s.NumGC.CIHigh-s.NumGC.Mean,
 			status)
-
-		configCount++
 	}
 
 	return table
 }
 
-func filterByPrefix(results []BenchmarkResult, prefix string) []BenchmarkResult {
-	filtered := []BenchmarkResult{}
-	for _, r := range results {
-		if len(r.Config.Name) >= len(prefix) && r.Config.Name[:len(prefix)] == prefix && r.Error == "" {
-			filtered = append(filtered, r)
+// filterByPrefix returns the summaries of configs named with prefix that
+// have at least one successful run
+func filterByPrefix(summaries []runner.BenchmarkSummary, prefix string) []runner.BenchmarkSummary {
+	filtered := []runner.BenchmarkSummary{}
+	for _, s := range summaries {
+		if strings.HasPrefix(s.Config.Name, prefix) && s.Duration.N > 0 {
+			filtered = append(filtered, s)
 		}
 	}
 	return filtered
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/agentmetrics"
+	"github.com/natalie/go-flags-eval/internal/runner"
+)
+
+// Size of the heap-vs-goal plots in characters
+const (
+	plotWidth  = 64
+	plotHeight = 12
+)
+
+// noGoal is the heap goal the runtime reports when GOGC=off and no memory
+// limit is set; such goals are left out of the plots
+const noGoal = 1 << 62
+
+func hasTimeline(results []runner.BenchmarkResult) bool {
+	for _, r := range results {
+		if len(r.Timeline) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// generateTimelineSection plots heap in use against the heap goal for the
+// first successful run of every task/config pair that recorded a timeline
+func generateTimelineSection(results []runner.BenchmarkResult) string {
+	section := "Sampled by the agents while they ran. `#` is the heap in use and `-` the heap goal, the size at which the next GC cycle starts; the heap climbs towards the goal and drops when a cycle finishes. A goal that hugs the heap means GOMEMLIMIT is setting the pace, a goal far above it means GOGC leaves the heap room to grow. Runs with GOGC=off and no limit have no goal.\n\n"
+
+	for _, group := range groupByTask(results, nil) {
+		picked := map[string]runner.BenchmarkResult{}
+		configs := []string{}
+		for _, r := range group.results {
+			if r.Error != "" || len(r.Timeline) == 0 {
+				continue
+			}
+			prev, ok := picked[r.Config.Name]
+			if !ok {
+				configs = append(configs, r.Config.Name)
+			}
+			if !ok || r.Repetition < prev.Repetition {
+				picked[r.Config.Name] = r
+			}
+		}
+
+		for _, config := range configs {
+			r := picked[config]
+			timeline := r.Timeline
+			last := timeline[len(timeline)-1]
+			peakHeap, peakGoal, peakRSS := uint64(0), uint64(0), uint64(0)
+			for _, s := range timeline {
+				peakHeap = max(peakHeap, s.HeapInUse)
+				if s.HeapGoal < noGoal {
+					peakGoal = max(peakGoal, s.HeapGoal)
+				}
+				peakRSS = max(peakRSS, s.RSS)
+			}
+
+			section += fmt.Sprintf("### %s / %s (run %d)\n\n", group.name, config, r.Repetition)
+			section += "```text\n"
+			section += plotHeapVsGoal(timeline)
+			section += "```\n\n"
+			section += fmt.Sprintf("%d samples over %v. Peak heap in use %.2f MB", len(timeline), last.Elapsed.Round(time.Millisecond), float64(peakHeap)/(1024*1024))
+			if peakGoal > 0 {
+				section += fmt.Sprintf(", peak goal %.2f MB", float64(peakGoal)/(1024*1024))
+			}
+			if peakRSS > 0 {
+				section += fmt.Sprintf(", peak RSS %.2f MB", float64(peakRSS)/(1024*1024))
+			}
+			section += fmt.Sprintf(", %d GC cycles, GC CPU %.1f%%.\n\n", last.NumGC-timeline[0].NumGC, last.GCCPUFraction*100)
+		}
+	}
+
+	return section
+}
+
+// plotHeapVsGoal draws the timeline as a character plot. Every column
+// covers an equal slice of time and shows the largest heap and goal
+// sampled in it; columns without samples repeat the previous one.
+func plotHeapVsGoal(timeline []agentmetrics.Sample) string {
+	end := timeline[len(timeline)-1].Elapsed
+	top := uint64(0)
+	for _, s := range timeline {
+		top = max(top, s.HeapInUse)
+		if s.HeapGoal < noGoal {
+			top = max(top, s.HeapGoal)
+		}
+	}
+	if top == 0 {
+		top = 1
+	}
+
+	heap := make([]uint64, plotWidth)
+	goal := make([]uint64, plotWidth)
+	seen := make([]bool, plotWidth)
+	for _, s := range timeline {
+		col := plotWidth - 1
+		if end > 0 {
+			col = min(int(int64(s.Elapsed)*plotWidth/int64(end)), plotWidth-1)
+		}
+		heap[col] = max(heap[col], s.HeapInUse)
+		if s.HeapGoal < noGoal {
+			goal[col] = max(goal[col], s.HeapGoal)
+		}
+		seen[col] = true
+	}
+	for col := 1; col < plotWidth; col++ {
+		if !seen[col] {
+			heap[col], goal[col] = heap[col-1], goal[col-1]
+		}
+	}
+
+	// level maps a value to a row counted from the bottom, -1 = not drawn
+	level := func(v uint64) int {
+		if v == 0 {
+			return -1
+		}
+		return min(int(float64(v)/float64(top)*plotHeight), plotHeight-1)
+	}
+
+	plot := ""
+	for row := plotHeight - 1; row >= 0; row-- {
+		label := ""
+		switch row {
+		case plotHeight - 1:
+			label = fmt.Sprintf("%.1f", float64(top)/(1024*1024))
+		case plotHeight / 2:
+			label = fmt.Sprintf("%.1f", float64(top)/(1024*1024)/2)
+		case 0:
+			label = "0"
+		}
+		line := make([]byte, plotWidth)
+		for col := range line {
+			switch {
+			case level(goal[col]) == row:
+				line[col] = '-'
+			case level(heap[col]) >= row:
+				line[col] = '#'
+			default:
+				line[col] = ' '
+			}
+		}
+		plot += fmt.Sprintf("%8s |%s\n", label, strings.TrimRight(string(line), " "))
+	}
+	plot += fmt.Sprintf("%8s +%s\n", "MB", strings.Repeat("-", plotWidth))
+	endLabel := end.Round(time.Millisecond).String()
+	plot += fmt.Sprintf("%8s  0s%*s\n", "", plotWidth-2, endLabel)
+	return plot
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/runner"
+)
+
+// Outcome is a measured config with its objective score
+type Outcome struct {
+	Config     runner.BenchmarkConfig
+	Env        []string // Environment variables applying the config
+	Score      float64
+	Feasible   bool
+	Violations []string `json:",omitempty"`
+	Summary    runner.BenchmarkSummary
+}
+
+// TuneOutput is the document written to the results file
+type TuneOutput struct {
+	Environment  runner.Environment
+	Task         string
+	Strategy     string
+	Objective    string
+	Requirements []string
+	Space        string
+	Constraints  []string
+	Seed         int64 `json:",omitempty"` // Candidate sampling seed for halving
+	Baseline     *Outcome
+	Best         *Outcome
+	Trajectory   []Step
+	Results      []runner.BenchmarkResult
+	Builds       []runner.AgentBuild
+}
+
+var (
+	outputFile     = flag.String("output", "tune_results.json", "Output file for the tuning results")
+	taskName       = flag.String("task", "", "Task to tune, from the plan or the built-in tasks")
+	planFile       = flag.String("plan", "", "JSON plan file to take the task from (its configs are ignored)")
+	spaceExpr      = flag.String("space", "", "Sweep expression spanning the search space (default: GOMAXPROCS powers of two up to the CPU count, GOGC=25..800:x2)")
+	constrain      = flag.String("constraints", "", "Semicolon-separated constraints pruning the space, e.g. \"GOGC=off => GOMEMLIMIT!=off\"")
+	strategy       = flag.String("strategy", "halving", "Search strategy: halving (successive halving) or hill-climb")
+	objectiveExpr  = flag.String("objective", "duration", "Metric to minimize (duration, cpu, pause, memory, rss, gc), or a weighted sum such as \"duration=1,rss=0.5\"")
+	requireExpr    = flag.String("require", "", "Semicolon-separated bounds on metric means a config must meet, e.g. \"rss<512MiB;duration<30s\"")
+	count          = flag.Int("count", 3, "Runs per config (hill-climb), or in the first round (halving)")
+	warmup         = flag.Int("warmup", 1, "Discarded warmup runs before a config's first measured run")
+	candidates     = flag.Int("candidates", 16, "Halving: configs sampled from larger spaces")
+	seed           = flag.Int64("seed", 0, "Halving: seed for sampling candidates (0 = random, the seed used is recorded)")
+	minGain        = flag.Float64("min-gain", 0.02, "Hill-climb: relative improvement required to move")
+	budget         = flag.Int("budget", 0, "Maximum number of measured runs (0 = unlimited)")
+	defaultTimeout = flag.Duration("timeout", 10*time.Minute, "Per-run timeout for tasks that do not set their own (0 = none)")
+	cgroupRoot     = flag.String("cgroup-root", "", "Delegated cgroup v2 directory; each run is placed in a transient leaf below it (Linux)")
+	procInterval   = flag.Duration("proc-interval", 50*time.Millisecond, "How often to sample /proc/<pid> of the running agent (Linux)")
+	buildDir       = flag.String("build-dir", filepath.Join(os.TempDir(), "go-flags-eval-agents"), "Directory where agent binaries are built and cached")
+	stageDir       = flag.String("stage-dir", "", "Directory where task inputs are copied for each run; on the inputs' filesystem, reflinks or hardlinks avoid full copies (default: system temp directory)")
+)
+
+func main() {
+	flag.Parse()
+
+	if *count < 1 {
+		log.Fatalf("Invalid -count=%d: must be at least 1", *count)
+	}
+	if *warmup < 0 {
+		log.Fatalf("Invalid -warmup=%d: must not be negative", *warmup)
+	}
+	if *candidates < 2 {
+		log.Fatalf("Invalid -candidates=%d: must be at least 2", *candidates)
+	}
+	if *strategy != "halving" && *strategy != "hill-climb" {
+		log.Fatalf("Unknown -strategy=%s (want halving or hill-climb)", *strategy)
+	}
+
+	objective, err := parseObjective(*objectiveExpr)
+	if err != nil {
+		log.Fatalf("Invalid -objective: %v", err)
+	}
+	requirements, err := parseRequirements(*requireExpr)
+	if err != nil {
+		log.Fatalf("Invalid -require: %v", err)
+	}
+
+	if *spaceExpr == "" {
+		*spaceExpr = fmt.Sprintf("GOMAXPROCS=1..%d:x2 GOGC=25..800:x2", runtime.NumCPU())
+	}
+	constraints := strings.Split(*constrain, ";")
+	space, err := runner.ParseSweep(*spaceExpr, constraints)
+	if err != nil {
+		log.Fatalf("Invalid -space: %v", err)
+	}
+	size := len(space.Expand())
+	if size == 0 {
+		log.Fatalf("Space is empty: all %d points are excluded by the constraints", space.Size())
+	}
+
+	task, err := selectTask()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	env := runner.CollectEnvironment()
+	fmt.Printf("Host: %s (%s/%s, %s, %d CPUs), %s\n", env.Hostname, env.GOOS, env.GOARCH, env.CPUModel, env.NumCPU, env.GoVersion)
+
+	// A co-tenancy group needs the binaries of its tenants
+	builds := []runner.AgentBuild{}
+	built := map[string]runner.AgentBuild{}
+	targets := []*runner.AgentTask{&task}
+	for i := range task.Tenants {
+		targets = append(targets, &task.Tenants[i])
+	}
+	for _, t := range targets {
+		if t.Package == "" {
+			continue
+		}
+		build, ok := built[t.Name]
+		if !ok {
+			build, err = runner.BuildTask(*buildDir, *t)
+			if err != nil {
+				log.Fatalf("Failed to build %s: %v", t.Name, err)
+			}
+			built[t.Name] = build
+			builds = append(builds, build)
+		}
+		t.Command, t.Dir = build.Binary, build.Dir
+	}
+
+	fmt.Printf("Tuning %s: minimize %s", task.Name, objective)
+	if len(requirements) > 0 {
+		fmt.Printf(" subject to %s", *requireExpr)
+	}
+	fmt.Printf(" over %d configurations (%s)\n", size, *spaceExpr)
+
+	t := &tuner{
+		ctx: ctx,
+		bench: &runner.Runner{
+			DefaultTimeout: *defaultTimeout,
+			CgroupRoot:     *cgroupRoot,
+			ProcInterval:   *procInterval,
+			StageDir:       *stageDir,
+		},
+		task:         task,
+		objective:    objective,
+		requirements: requirements,
+		warmup:       *warmup,
+		budget:       *budget,
+		evals:        map[string]*evaluation{},
+	}
+
+	// Everything is compared with the runtime defaults
+	fmt.Println("\n=== Baseline ===")
+	t.baseline, err = t.measure(runner.BenchmarkConfig{Name: "default", GCPercent: 100}, *count)
+	if err == nil {
+		t.record(0, "baseline", t.baseline)
+	}
+
+	var best *evaluation
+	var runSeed int64
+	if err == nil {
+		switch *strategy {
+		case "halving":
+			runSeed = *seed
+			if runSeed == 0 {
+				runSeed = time.Now().UnixNano()
+			}
+			best, err = t.successiveHalving(space, *count, *candidates, rand.New(rand.NewSource(runSeed)))
+		case "hill-climb":
+			best, err = t.hillClimb(space, *count, *minGain)
+		}
+	}
+	switch {
+	case errors.Is(err, errBudget):
+		fmt.Printf("\nStopped after %d runs: budget exhausted\n", t.runs)
+	case err != nil && ctx.Err() != nil:
+		fmt.Printf("\nInterrupted after %d runs\n", t.runs)
+	case err != nil:
+		log.Fatalf("Search failed: %v", err)
+	}
+
+	output := TuneOutput{
+		Environment:  env,
+		Task:         task.Name,
+		Strategy:     *strategy,
+		Objective:    objective.String(),
+		Requirements: requirementTexts(requirements),
+		Space:        *spaceExpr,
+		Constraints:  nonEmpty(constraints),
+		Seed:         runSeed,
+		Baseline:     outcome(t.baseline),
+		Best:         outcome(best),
+		Trajectory:   t.trajectory,
+		Results:      t.results,
+		Builds:       builds,
+	}
+	if err := saveResults(*outputFile, output); err != nil {
+		log.Fatalf("Failed to save results: %v", err)
+	}
+	fmt.Printf("\n\nResults saved to: %s\n", *outputFile)
+
+	printBest(objective, t.baseline, best, t.runs)
+	if ctx.Err() != nil {
+		os.Exit(130)
+	}
+}
+
+// selectTask picks the -task from the plan or the built-in tasks. Without
+// -task, a plan with a single task is used as is.
+func selectTask() (runner.AgentTask, error) {
+	tasks := runner.DefaultTasks()
+	if *planFile != "" {
+		plan, err := runner.LoadPlan(*planFile)
+		if err != nil {
+			return runner.AgentTask{}, fmt.Errorf("failed to load plan: %w", err)
+		}
+		tasks = plan.AgentTasks()
+	}
+
+	names := []string{}
+	for _, task := range tasks {
+		if task.Name == *taskName || (*taskName == "" && len(tasks) == 1) {
+			return task, nil
+		}
+		names = append(names, task.Name)
+	}
+	if *taskName == "" {
+		return runner.AgentTask{}, fmt.Errorf("-task is required (one of %s)", strings.Join(names, ", "))
+	}
+	return runner.AgentTask{}, fmt.Errorf("unknown task %s (want one of %s)", *taskName, strings.Join(names, ", "))
+}
+
+func outcome(e *evaluation) *Outcome {
+	if e == nil {
+		return nil
+	}
+	return &Outcome{
+		Config:     e.cfg,
+		Env:        runner.FlagEnv(e.cfg),
+		Score:      e.score,
+		Feasible:   e.feasible,
+		Violations: e.violations,
+		Summary:    e.summary,
+	}
+}
+
+func requirementTexts(reqs []requirement) []string {
+	texts := make([]string, len(reqs))
+	for i, r := range reqs {
+		texts[i] = r.text
+	}
+	return texts
+}
+
+func nonEmpty(items []string) []string {
+	out := []string{}
+	for _, s := range items {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func saveResults(filename string, output TuneOutput) error {
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal results: %w", err)
+	}
+
+	return os.WriteFile(filename, data, 0644)
+}
+
+func printBest(objective Objective, baseline, best *evaluation, runs int) {
+	fmt.Println("\n=== Best Configuration ===")
+	if best == nil || !best.feasible {
+		fmt.Printf("No configuration met the requirements in %d runs\n", runs)
+		return
+	}
+
+	fmt.Printf("%s: %s = %s", best.cfg.Name, objective, objective.Format(best.score))
+	if baseline != nil && baseline.feasible && baseline.score > 0 {
+		fmt.Printf(" (default %s, %+.1f%%)", objective.Format(baseline.score), (best.score/baseline.score-1)*100)
+	}
+	fmt.Printf(" after %d runs\n", runs)
+	fmt.Printf("  %s\n", best.summary)
+
+	env := runner.FlagEnv(best.cfg)
+	if len(env) == 0 {
+		fmt.Println("The runtime defaults are best: no environment variables needed")
+		return
+	}
+	fmt.Println("Environment:")
+	fmt.Printf("  %s\n", strings.Join(env, " "))
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/runner"
+)
+
+// metric is one measured quantity the tuner can optimize or bound,
+// taken as the mean over a config's successful runs
+type metric struct {
+	mean   func(runner.BenchmarkSummary) float64
+	parse  func(string) (float64, error)
+	format func(float64) string
+}
+
+var metrics = map[string]metric{
+	"duration": {
+		mean:   func(s runner.BenchmarkSummary) float64 { return s.Duration.Mean },
+		parse:  parseNanos,
+		format: formatNanos,
+	},
+	"cpu": {
+		mean:   func(s runner.BenchmarkSummary) float64 { return s.CPUTime.Mean },
+		parse:  parseNanos,
+		format: formatNanos,
+	},
+	"pause": {
+		mean:   func(s runner.BenchmarkSummary) float64 { return s.PauseTimeNs.Mean },
+		parse:  parseNanos,
+		format: formatNanos,
+	},
+	"memory": {
+		mean:   func(s runner.BenchmarkSummary) float64 { return s.MemoryAllocated.Mean },
+		parse:  parseBytes,
+		format: formatBytes,
+	},
+	"rss": {
+		mean:   func(s runner.BenchmarkSummary) float64 { return s.PeakRSS.Mean },
+		parse:  parseBytes,
+		format: formatBytes,
+	},
+	"gc": {
+		mean:   func(s runner.BenchmarkSummary) float64 { return s.NumGC.Mean },
+		parse:  func(s string) (float64, error) { return strconv.ParseFloat(s, 64) },
+		format: func(v float64) string { return fmt.Sprintf("%.1f", v) },
+	},
+}
+
+const metricNames = "duration, cpu, pause, memory, rss, gc"
+
+func lookupMetric(name string) (metric, error) {
+	m, ok := metrics[strings.ToLower(name)]
+	if !ok {
+		return metric{}, fmt.Errorf("unknown metric %q (want one of %s)", name, metricNames)
+	}
+	return m, nil
+}
+
+func parseNanos(s string) (float64, error) {
+	d, err := time.ParseDuration(s)
+	return float64(d), err
+}
+
+func formatNanos(v float64) string {
+	return time.Duration(v).Round(time.Microsecond).String()
+}
+
+func parseBytes(s string) (float64, error) {
+	v, err := runner.ParseByteSize(s)
+	return float64(v), err
+}
+
+func formatBytes(v float64) string {
+	return fmt.Sprintf("%.2f MB", v/(1024*1024))
+}
+
+type term struct {
+	name   string
+	weight float64
+}
+
+// Objective is the quantity the tuner minimizes: a single metric such as
+// "duration", or a weighted sum such as "duration=1,rss=0.5". In a sum
+// every metric is taken relative to the baseline config, so the units
+// cancel and the baseline scores 1.
+type Objective struct {
+	terms []term
+}
+
+func parseObjective(s string) (Objective, error) {
+	var o Objective
+	seen := map[string]bool{}
+	for _, item := range strings.Split(s, ",") {
+		name, weightStr, hasWeight := strings.Cut(strings.TrimSpace(item), "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, err := lookupMetric(name); err != nil {
+			return Objective{}, err
+		}
+		if seen[name] {
+			return Objective{}, fmt.Errorf("metric %s given more than once", name)
+		}
+		seen[name] = true
+
+		weight := 1.0
+		if hasWeight {
+			w, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+			if err != nil || w <= 0 {
+				return Objective{}, fmt.Errorf("invalid weight %q for %s", weightStr, name)
+			}
+			weight = w
+		}
+		o.terms = append(o.terms, term{name, weight})
+	}
+	return o, nil
+}
+
+func (o Objective) String() string {
+	if len(o.terms) == 1 {
+		return o.terms[0].name
+	}
+	parts := make([]string, len(o.terms))
+	for i, t := range o.terms {
+		parts[i] = fmt.Sprintf("%s=%g", t.name, t.weight)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Score returns the objective for s. baseline is only used by weighted
+// objectives.
+func (o Objective) Score(s, baseline runner.BenchmarkSummary) float64 {
+	if len(o.terms) == 1 {
+		return metrics[o.terms[0].name].mean(s)
+	}
+
+	var score, total float64
+	for _, t := range o.terms {
+		m := metrics[t.name]
+		value, base := m.mean(s), m.mean(baseline)
+		if base > 0 {
+			value /= base
+		}
+		score += t.weight * value
+		total += t.weight
+	}
+	return score / total
+}
+
+// Format renders a score in the unit of the objective
+func (o Objective) Format(score float64) string {
+	if len(o.terms) == 1 {
+		return metrics[o.terms[0].name].format(score)
+	}
+	return fmt.Sprintf("%.3f", score)
+}
+
+// requirement bounds the mean of one metric, e.g. "rss<512MiB"
+type requirement struct {
+	text   string
+	metric metric
+	op     string
+	value  float64
+}
+
+// parseRequirements parses semicolon-separated requirements
+func parseRequirements(s string) ([]requirement, error) {
+	reqs := []requirement{}
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		req, err := parseRequirement(item)
+		if err != nil {
+			return nil, fmt.Errorf("requirement %q: %w", item, err)
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+func parseRequirement(s string) (requirement, error) {
+	// Longest operators first so "<=" is not read as "<"
+	for _, op := range []string{"<=", ">=", "<", ">"} {
+		name, valueStr, ok := strings.Cut(s, op)
+		if !ok {
+			continue
+		}
+		m, err := lookupMetric(strings.TrimSpace(name))
+		if err != nil {
+			return requirement{}, err
+		}
+		value, err := m.parse(strings.TrimSpace(valueStr))
+		if err != nil {
+			return requirement{}, fmt.Errorf("invalid value %q: %w", valueStr, err)
+		}
+		return requirement{text: s, metric: m, op: op, value: value}, nil
+	}
+	return requirement{}, fmt.Errorf("expected a comparison such as rss<512MiB")
+}
+
+func (r requirement) met(s runner.BenchmarkSummary) bool {
+	v := r.metric.mean(s)
+	switch r.op {
+	case "<":
+		return v < r.value
+	case "<=":
+		return v <= r.value
+	case ">":
+		return v > r.value
+	default:
+		return v >= r.value
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/natalie/go-flags-eval/internal/runner"
+)
+
+// errBudget stops a search once the run budget is spent
+var errBudget = errors.New("run budget exhausted")
+
+// evaluation is everything measured for one config so far
+type evaluation struct {
+	cfg        runner.BenchmarkConfig
+	samples    []runner.BenchmarkResult
+	summary    runner.BenchmarkSummary
+	score      float64
+	feasible   bool
+	violations []string
+}
+
+// better reports whether a ranks before b: feasible configs first, then
+// by score
+func better(a, b *evaluation) bool {
+	if a.feasible != b.feasible {
+		return a.feasible
+	}
+	return a.score < b.score
+}
+
+// Step is one entry of the search trajectory
+type Step struct {
+	Round      int
+	Action     string // baseline, start, neighbor, moved, kept, dropped
+	Config     string
+	Runs       int
+	Score      float64
+	Feasible   bool
+	Violations []string `json:",omitempty"`
+}
+
+// tuner measures configs of one task on demand, caching every sample so
+// that no config is run more often than a strategy asks for
+type tuner struct {
+	ctx          context.Context
+	bench        *runner.Runner
+	task         runner.AgentTask
+	objective    Objective
+	requirements []requirement
+	warmup       int
+	budget       int // Maximum measured runs, 0 = unlimited
+
+	runs       int
+	baseline   *evaluation
+	evals      map[string]*evaluation
+	results    []runner.BenchmarkResult
+	trajectory []Step
+}
+
+// measure runs cfg until it has at least n samples and rescores it
+func (t *tuner) measure(cfg runner.BenchmarkConfig, n int) (*evaluation, error) {
+	e, ok := t.evals[cfg.Name]
+	if !ok {
+		e = &evaluation{cfg: cfg}
+		t.evals[cfg.Name] = e
+	}
+	if len(e.samples) >= n {
+		return e, nil
+	}
+
+	fmt.Printf("Evaluating %s [%d -> %d runs]... ", cfg.Name, len(e.samples), n)
+	if len(e.samples) == 0 {
+		for w := 0; w < t.warmup; w++ {
+			t.bench.Run(t.ctx, t.task, cfg)
+		}
+	}
+	var err error
+	for len(e.samples) < n {
+		if err = t.ctx.Err(); err != nil {
+			break
+		}
+		if t.budget > 0 && t.runs >= t.budget {
+			err = errBudget
+			break
+		}
+		result := t.bench.Run(t.ctx, t.task, cfg)
+		if t.ctx.Err() != nil {
+			// Killed by the interrupt, not by its config
+			continue
+		}
+		t.runs++
+		result.Repetition = len(e.samples) + 1
+		result.Sequence = t.runs
+		e.samples = append(e.samples, result)
+		t.results = append(t.results, result)
+	}
+
+	t.score(e)
+	if e.feasible {
+		fmt.Printf("%s = %s\n", t.objective, t.objective.Format(e.score))
+	} else {
+		fmt.Printf("infeasible: %v\n", e.violations)
+	}
+	return e, err
+}
+
+// score summarizes e's samples and checks them against the requirements.
+// A config with any failed run is infeasible.
+func (t *tuner) score(e *evaluation) {
+	e.summary = runner.Summarize(t.task.Name, e.cfg, e.samples)
+	e.violations = nil
+	if e.summary.Failures > 0 {
+		e.violations = append(e.violations, fmt.Sprintf("%d of %d runs failed", e.summary.Failures, e.summary.Runs))
+	}
+	if e.summary.Duration.N > 0 {
+		for _, req := range t.requirements {
+			if !req.met(e.summary) {
+				e.violations = append(e.violations, req.text)
+			}
+		}
+	}
+	e.feasible = len(e.violations) == 0 && e.summary.Duration.N > 0
+
+	baseline := e.summary
+	if t.baseline != nil {
+		baseline = t.baseline.summary
+	}
+	e.score = t.objective.Score(e.summary, baseline)
+}
+
+func (t *tuner) record(round int, action string, e *evaluation) {
+	t.trajectory = append(t.trajectory, Step{
+		Round:      round,
+		Action:     action,
+		Config:     e.cfg.Name,
+		Runs:       len(e.samples),
+		Score:      e.score,
+		Feasible:   e.feasible,
+		Violations: e.violations,
+	})
+}
+
+// successiveHalving measures every candidate count times, keeps the
+// better half, doubles the runs and repeats until one config is left.
+// Spaces larger than maxCandidates are sampled with rng first.
+func (t *tuner) successiveHalving(space *runner.Sweep, count, maxCandidates int, rng *rand.Rand) (*evaluation, error) {
+	configs := space.Expand()
+	if total := len(configs); total > maxCandidates {
+		rng.Shuffle(total, func(i, j int) { configs[i], configs[j] = configs[j], configs[i] })
+		configs = configs[:maxCandidates]
+		fmt.Printf("Sampled %d of %d configurations\n", maxCandidates, total)
+	}
+
+	candidates := []*evaluation{}
+	n := count
+	for round := 1; len(configs) > 1; round++ {
+		fmt.Printf("\n=== Round %d: %d configurations, %d runs each ===\n", round, len(configs), n)
+		candidates = candidates[:0]
+		for _, cfg := range configs {
+			e, err := t.measure(cfg, n)
+			candidates = append(candidates, e)
+			if err != nil {
+				return bestOf(candidates), err
+			}
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool { return better(candidates[i], candidates[j]) })
+		keep := (len(candidates) + 1) / 2
+		configs = configs[:0]
+		for i, e := range candidates {
+			if i < keep {
+				t.record(round, "kept", e)
+				configs = append(configs, e.cfg)
+			} else {
+				t.record(round, "dropped", e)
+			}
+		}
+		n *= 2
+	}
+
+	return t.measure(configs[0], count)
+}
+
+// hillClimb starts in the middle of the space and repeatedly measures
+// the neighbors one value up and down each flag, moving to the best of
+// them while it improves the score by more than minGain
+func (t *tuner) hillClimb(space *runner.Sweep, count int, minGain float64) (*evaluation, error) {
+	point, err := startPoint(space)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _ := space.Point(point)
+	current, err := t.measure(cfg, count)
+	if err != nil {
+		return current, err
+	}
+	t.record(0, "start", current)
+
+	shape := space.Shape()
+	for round := 1; ; round++ {
+		fmt.Printf("\n=== Round %d: neighbors of %s ===\n", round, current.cfg.Name)
+		var best *evaluation
+		var bestPoint []int
+		for dim := range shape {
+			for _, delta := range []int{-1, 1} {
+				p := append([]int(nil), point...)
+				p[dim] += delta
+				if p[dim] < 0 || p[dim] >= shape[dim] {
+					continue
+				}
+				cfg, ok := space.Point(p)
+				if !ok || cfg.Name == current.cfg.Name {
+					continue
+				}
+				e, err := t.measure(cfg, count)
+				if err != nil {
+					return bestOf([]*evaluation{current, e}), err
+				}
+				t.record(round, "neighbor", e)
+				if best == nil || better(e, best) {
+					best, bestPoint = e, p
+				}
+			}
+		}
+
+		if best == nil || !improves(best, current, minGain) {
+			return current, nil
+		}
+		point, current = bestPoint, best
+		t.record(round, "moved", current)
+	}
+}
+
+// improves reports whether moving from cur to next is worth it. Any
+// feasible config beats an infeasible one; otherwise the score has to
+// drop by more than minGain, so that noise alone does not cause a move.
+func improves(next, cur *evaluation, minGain float64) bool {
+	if next.feasible != cur.feasible {
+		return next.feasible
+	}
+	return next.score < cur.score*(1-minGain)
+}
+
+// startPoint returns the middle of every flag's value list, or the first
+// point satisfying the constraints if the middle does not
+func startPoint(space *runner.Sweep) ([]int, error) {
+	shape := space.Shape()
+	point := make([]int, len(shape))
+	for i, n := range shape {
+		point[i] = n / 2
+	}
+	if _, ok := space.Point(point); ok {
+		return point, nil
+	}
+
+	point = make([]int, len(shape))
+	for {
+		if _, ok := space.Point(point); ok {
+			return point, nil
+		}
+		// Advance the last index fastest, like an odometer
+		i := len(point) - 1
+		for ; i >= 0; i-- {
+			point[i]++
+			if point[i] < shape[i] {
+				break
+			}
+			point[i] = 0
+		}
+		if i < 0 {
+			return nil, errors.New("every point of the space is excluded by the constraints")
+		}
+	}
+}
+
+func bestOf(evals []*evaluation) *evaluation {
+	var best *evaluation
+	for _, e := range evals {
+		if e != nil && len(e.samples) > 0 && (best == nil || better(e, best)) {
+			best = e
+		}
+	}
+	return best
+}
@@ -4,7 +4,7 @@
 
 set -e
 
-EVAL_BIN="../cmd/eval/main.go"
+EVAL_BIN="../cmd/eval"
 DURATION="30s"
 WORKLOAD="memory"
 OUTPUT_DIR="./results/memory-limits"
@@ -0,0 +1,92 @@
+package agentharness
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Env is what a workload gets from the harness
+type Env struct {
+	Workers     int   // -workers, GOMAXPROCS by default
+	Seed        int64 // -seed, random by default
+	SteadyState bool  // -duration is set, so Run is called for many passes
+
+	mu     sync.Mutex
+	phases map[string]time.Duration
+}
+
+// Phase starts timing the named phase of a pass and returns the function
+// that stops it, so a phase can be timed with
+//
+//	defer env.Phase("parse")()
+//
+// Time spent in a phase adds up over all measured passes.
+func (e *Env) Phase(name string) func() {
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		e.mu.Lock()
+		e.phases[name] += elapsed
+		e.mu.Unlock()
+	}
+}
+
+// Phases returns the time spent in each phase so far
+func (e *Env) Phases() map[string]time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	phases := make(map[string]time.Duration, len(e.phases))
+	for name, d := range e.phases {
+		phases[name] = d
+	}
+	return phases
+}
+
+func (e *Env) resetPhases() {
+	e.mu.Lock()
+	e.phases = map[string]time.Duration{}
+	e.mu.Unlock()
+}
+
+// Rand returns a random source for the i-th unit of work. Sources depend
+// only on the seed and i, so units draw the same numbers however they are
+// scheduled onto workers.
+func (e *Env) Rand(i int) *rand.Rand {
+	return rand.New(rand.NewSource(e.Seed + int64(i)))
+}
+
+// ForEach calls fn(i) for every i in [0, n) on at most Workers goroutines
+// at a time and returns when all calls have
+func (e *Env) ForEach(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, e.Workers)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// FindGoFiles returns every .go file below dir
+func FindGoFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".go") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
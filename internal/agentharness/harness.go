@@ -0,0 +1,234 @@
+// Package agentharness runs an agent's workload with everything around
+// it that all agents share: the common flags, the configuration banner,
+// single-pass and steady-state measurement, phase timing, the sampler,
+// profiles and the metrics file. An agent only provides its Workload.
+package agentharness
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/agentmetrics"
+)
+
+// Workload is what an agent does. Run performs one pass: once per process
+// by default, or repeatedly with -duration.
+type Workload interface {
+	Run(env *Env) error
+}
+
+// WorkloadFunc adapts a function to the Workload interface
+type WorkloadFunc func(env *Env) error
+
+func (f WorkloadFunc) Run(env *Env) error {
+	return f(env)
+}
+
+// A Workload may also implement any of the following interfaces.
+
+// Describer lists the agent's own settings for the banner, as
+// "Name: value" lines
+type Describer interface {
+	Describe() []string
+}
+
+// SetupWorkload prepares state once before the first pass, outside the
+// measurement
+type SetupWorkload interface {
+	Setup(env *Env) error
+}
+
+// Reporter prints the agent's results and fills its fields of the
+// metrics, such as FilesProcessed and Custom, after the last pass
+type Reporter interface {
+	Report(metrics *agentmetrics.Metrics)
+}
+
+// Agent describes an agent binary
+type Agent struct {
+	Title    string // Banner heading, e.g. "AST Parser Agent"
+	Workload Workload
+}
+
+var (
+	metricsOutput = flag.String("metrics-output", "", "File to write performance metrics (JSON)")
+	sampleEvery   = flag.Duration("sample-interval", 100*time.Millisecond, "How often to sample heap and GC into the metrics timeline (0 = no timeline)")
+	workers       = flag.Int("workers", 0, "Number of worker goroutines (0 = GOMAXPROCS)")
+	seed          = flag.Int64("seed", 0, "Seed for the workload's random choices (0 = random, the seed used is recorded)")
+	duration      = flag.Duration("duration", 0, "Run the workload repeatedly for this long after warmup and report throughput (0 = run once)")
+	warmup        = flag.Duration("warmup", 5*time.Second, "Time to run the workload before measuring when -duration is set")
+	cpuProfile    = flag.String("cpuprofile", "", "Write a CPU profile of the whole run to this file")
+	memProfile    = flag.String("memprofile", "", "Write a heap profile at exit to this file")
+)
+
+// Main parses the flags, runs the agent's workload and writes its metrics.
+// It exits the process on errors.
+func Main(agent Agent) {
+	flag.Parse()
+
+	var sampler *agentmetrics.Sampler
+	if *metricsOutput != "" && *sampleEvery > 0 {
+		sampler = agentmetrics.StartSampler(*sampleEvery)
+	}
+
+	if *workers <= 0 {
+		*workers = runtime.GOMAXPROCS(-1)
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	env := &Env{
+		Workers:     *workers,
+		Seed:        *seed,
+		SteadyState: *duration > 0,
+		phases:      map[string]time.Duration{},
+	}
+	w := agent.Workload
+
+	// Report configuration
+	fmt.Printf("%s\n", agent.Title)
+	fmt.Printf("%s\n", strings.Repeat("=", len(agent.Title)))
+	fmt.Printf("GOMAXPROCS: %d\n", runtime.GOMAXPROCS(-1))
+	gcVal := debug.SetGCPercent(-1)
+	debug.SetGCPercent(gcVal)
+	fmt.Printf("GOGC: %d\n", gcVal)
+	fmt.Printf("Workers: %d\n", env.Workers)
+	fmt.Printf("Seed: %d\n", env.Seed)
+	if d, ok := w.(Describer); ok {
+		for _, line := range d.Describe() {
+			fmt.Printf("%s\n", line)
+		}
+	}
+	fmt.Printf("\n")
+
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			log.Fatalf("Failed to create CPU profile: %v", err)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			log.Fatalf("Failed to start CPU profile: %v", err)
+		}
+	}
+
+	if s, ok := w.(SetupWorkload); ok {
+		stop := env.Phase("setup")
+		err := s.Setup(env)
+		stop()
+		if err != nil {
+			log.Fatalf("Failed to set up: %v", err)
+		}
+	}
+	setup := env.phases["setup"]
+
+	run := func() {
+		if err := w.Run(env); err != nil {
+			log.Fatalf("Workload failed: %v", err)
+		}
+	}
+
+	var metrics *agentmetrics.Metrics
+	if *duration > 0 {
+		fmt.Printf("Running for %v after %v warmup\n", *duration, *warmup)
+		// Warm up here rather than in RunSteadyState, so that phase
+		// timings cover the measured window only
+		for start := time.Now(); time.Since(start) < *warmup; {
+			run()
+		}
+		env.resetPhases()
+		metrics = agentmetrics.RunSteadyState(0, *duration, run)
+		metrics.SteadyState.Warmup = *warmup
+	} else {
+		start := time.Now()
+		run()
+		elapsed := time.Since(start)
+
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		metrics = &agentmetrics.Metrics{
+			Duration:        elapsed,
+			MemoryAllocated: ms.TotalAlloc,
+			HeapAllocated:   ms.HeapAlloc,
+			NumGC:           ms.NumGC,
+			PauseTimeNs:     ms.PauseTotalNs,
+			Goroutines:      runtime.NumGoroutine(),
+		}
+	}
+	if *cpuProfile != "" {
+		pprof.StopCPUProfile()
+	}
+	metrics.Seed = env.Seed
+	metrics.Phases = env.Phases()
+	if setup > 0 {
+		metrics.Phases["setup"] = setup
+	}
+
+	// Print results
+	fmt.Printf("\nResults:\n")
+	fmt.Printf("========\n")
+	if r, ok := w.(Reporter); ok {
+		r.Report(metrics)
+	}
+	fmt.Printf("Duration: %v\n", metrics.Duration)
+	fmt.Printf("Memory allocated: %.2f MB\n", float64(metrics.MemoryAllocated)/(1024*1024))
+	fmt.Printf("Heap allocated: %.2f MB\n", float64(metrics.HeapAllocated)/(1024*1024))
+	fmt.Printf("GC runs: %d\n", metrics.NumGC)
+	fmt.Printf("Goroutines: %d\n", metrics.Goroutines)
+	if metrics.SteadyState != nil {
+		metrics.SteadyState.Print()
+	}
+	printPhases(metrics.Phases)
+
+	if *memProfile != "" {
+		if err := writeHeapProfile(*memProfile); err != nil {
+			log.Printf("Failed to write heap profile: %v", err)
+		}
+	}
+
+	// Write metrics to file if requested
+	if *metricsOutput != "" {
+		metrics.Runtime = agentmetrics.ReadRuntimeMetrics()
+		metrics.Timeline = sampler.Stop()
+		if err := metrics.WriteToFile(*metricsOutput); err != nil {
+			log.Printf("Failed to write metrics: %v", err)
+		}
+	}
+}
+
+// printPhases prints the phase timings, longest first
+func printPhases(phases map[string]time.Duration) {
+	if len(phases) == 0 {
+		return
+	}
+	names := make([]string, 0, len(phases))
+	for name := range phases {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return phases[names[i]] > phases[names[j]]
+	})
+	fmt.Printf("Phases:\n")
+	for _, name := range names {
+		fmt.Printf("  %s: %v\n", name, phases[name].Round(time.Microsecond))
+	}
+}
+
+func writeHeapProfile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// Collect first so the profile reflects live objects at exit
+	runtime.GC()
+	return pprof.WriteHeapProfile(f)
+}
@@ -2,12 +2,17 @@ package agentmetrics
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"time"
+
+	"github.com/natalie/go-flags-eval/internal/jsonschema"
 )
 
 // Metrics represents performance metrics collected by an agent
 type Metrics struct {
+	SchemaVersion int `json:"schema_version"` // Set by WriteToFile, see SchemaVersion
+
 	Duration        time.Duration `json:"duration"`
 	MemoryAllocated uint64        `json:"memory_allocated"` // Total bytes allocated
 	HeapAllocated   uint64        `json:"heap_allocated"`   // Current heap size
@@ -19,10 +24,25 @@ type Metrics struct {
 	TasksCompleted  int            `json:"tasks_completed,omitempty"`
 	FilesProcessed  int            `json:"files_processed,omitempty"`
 	Custom          map[string]any `json:"custom,omitempty"`
+
+	// Set by agents built on agentharness
+	Seed   int64                    `json:"seed,omitempty"`   // Of the workload's random choices
+	Phases map[string]time.Duration `json:"phases,omitempty"` // Time in each phase of the measured passes
+
+	// Set by -duration runs, whose allocation and GC figures above then
+	// cover the steady-state window only
+	SteadyState *SteadyState `json:"steady_state,omitempty"`
+
+	// Every runtime/metrics value at exit, see ReadRuntimeMetrics
+	Runtime *RuntimeMetrics `json:"runtime,omitempty"`
+
+	// Heap and GC over time, see StartSampler
+	Timeline []Sample `json:"timeline,omitempty"`
 }
 
 // WriteToFile writes metrics to a JSON file
 func (m *Metrics) WriteToFile(filename string) error {
+	m.SchemaVersion = SchemaVersion
 	data, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
 		return err
@@ -31,17 +51,44 @@ func (m *Metrics) WriteToFile(filename string) error {
 	return os.WriteFile(filename, data, 0644)
 }
 
-// ReadFromFile reads metrics from a JSON file
+// ReadFromFile reads metrics from a JSON file and validates them against
+// Schema. Files from before versioning are read as the first version,
+// whose layout they share; files of a newer version are rejected.
 func ReadFromFile(filename string) (*Metrics, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
+	v, err := jsonschema.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("metrics must be a JSON object")
+	}
+	version := 0
+	if n, ok := doc["schema_version"].(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			version = int(i)
+		}
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("metrics schema version %d is newer than the supported version %d", version, SchemaVersion)
+	}
+	if version == 0 {
+		doc["schema_version"] = json.Number("1")
+	}
+	if err := Schema().Validate(doc); err != nil {
+		return nil, err
+	}
+
 	var metrics Metrics
 	if err := json.Unmarshal(data, &metrics); err != nil {
 		return nil, err
 	}
+	metrics.SchemaVersion = SchemaVersion
 
 	return &metrics, nil
 }
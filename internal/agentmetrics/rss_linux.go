@@ -0,0 +1,25 @@
+package agentmetrics
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readRSS returns the resident set size of this process from
+// /proc/self/statm, or 0 if it cannot be read
+func readRSS() uint64 {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * uint64(os.Getpagesize())
+}
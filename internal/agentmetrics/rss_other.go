@@ -0,0 +1,8 @@
+//go:build !linux
+
+package agentmetrics
+
+// readRSS is not supported outside Linux
+func readRSS() uint64 {
+	return 0
+}
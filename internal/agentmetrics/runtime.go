@@ -0,0 +1,161 @@
+package agentmetrics
+
+import (
+	"encoding/json"
+	"math"
+	"runtime/metrics"
+	"strings"
+)
+
+// Names of the runtime/metrics the summaries are derived from
+const (
+	MetricGCCPU          = "/cpu/classes/gc/total:cpu-seconds"
+	MetricTotalCPU       = "/cpu/classes/total:cpu-seconds"
+	MetricHeapGoal       = "/gc/heap/goal:bytes"
+	MetricLimiterLast    = "/gc/limiter/last-enabled:gc-cycle"
+	MetricGCPauses       = "/sched/pauses/total/gc:seconds"
+	MetricSchedLatency   = "/sched/latencies:seconds"
+	metricGCPausesLegacy = "/gc/pauses:seconds" // Before Go 1.22
+)
+
+// RuntimeMetrics is every metric of the runtime/metrics package, read
+// once at exit, keyed by its full name such as "/gc/heap/goal:bytes"
+type RuntimeMetrics struct {
+	Uint64     map[string]uint64     `json:"uint64"`
+	Float64    map[string]float64    `json:"float64"`
+	Histograms map[string]*Histogram `json:"histograms"`
+}
+
+// Histogram is a runtime/metrics Float64Histogram: Counts[i] samples fell
+// into [Buckets[i], Buckets[i+1])
+type Histogram struct {
+	Counts  []uint64 `json:"counts"`
+	Buckets []Bound  `json:"buckets"`
+}
+
+// Bound is a histogram bucket boundary. The outermost boundaries may be
+// infinite, which JSON numbers cannot represent, so those are encoded as
+// the strings "-Inf" and "+Inf".
+type Bound float64
+
+func (b Bound) MarshalJSON() ([]byte, error) {
+	switch {
+	case math.IsInf(float64(b), 1):
+		return []byte(`"+Inf"`), nil
+	case math.IsInf(float64(b), -1):
+		return []byte(`"-Inf"`), nil
+	}
+	return json.Marshal(float64(b))
+}
+
+func (b *Bound) UnmarshalJSON(data []byte) error {
+	switch strings.TrimSpace(string(data)) {
+	case `"+Inf"`:
+		*b = Bound(math.Inf(1))
+		return nil
+	case `"-Inf"`:
+		*b = Bound(math.Inf(-1))
+		return nil
+	}
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	*b = Bound(f)
+	return nil
+}
+
+// ReadRuntimeMetrics reads every metric the running Go version supports
+func ReadRuntimeMetrics() *RuntimeMetrics {
+	descs := metrics.All()
+	samples := make([]metrics.Sample, len(descs))
+	for i, d := range descs {
+		samples[i].Name = d.Name
+	}
+	metrics.Read(samples)
+
+	rm := &RuntimeMetrics{
+		Uint64:     map[string]uint64{},
+		Float64:    map[string]float64{},
+		Histograms: map[string]*Histogram{},
+	}
+	for _, s := range samples {
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			rm.Uint64[s.Name] = s.Value.Uint64()
+		case metrics.KindFloat64:
+			rm.Float64[s.Name] = s.Value.Float64()
+		case metrics.KindFloat64Histogram:
+			h := s.Value.Float64Histogram()
+			hist := &Histogram{
+				Counts:  append([]uint64{}, h.Counts...),
+				Buckets: make([]Bound, len(h.Buckets)),
+			}
+			for i, b := range h.Buckets {
+				hist.Buckets[i] = Bound(b)
+			}
+			rm.Histograms[s.Name] = hist
+		}
+	}
+	return rm
+}
+
+// GCCPUFraction is the share of the CPU time available to the process
+// (GOMAXPROCS times wall time) that the GC used, or 0 if unknown
+func (rm *RuntimeMetrics) GCCPUFraction() float64 {
+	total := rm.Float64[MetricTotalCPU]
+	if total == 0 {
+		return 0
+	}
+	return rm.Float64[MetricGCCPU] / total
+}
+
+// LimiterEnabled reports whether the GC CPU limiter, which caps the GC's
+// share of the CPU when GOMEMLIMIT forces back-to-back cycles, ever
+// kicked in
+func (rm *RuntimeMetrics) LimiterEnabled() bool {
+	return rm.Uint64[MetricLimiterLast] > 0
+}
+
+// GCPauses returns the distribution of stop-the-world GC pauses
+func (rm *RuntimeMetrics) GCPauses() *Histogram {
+	if h := rm.Histograms[MetricGCPauses]; h != nil {
+		return h
+	}
+	return rm.Histograms[metricGCPausesLegacy]
+}
+
+// Total returns the number of samples in the histogram
+func (h *Histogram) Total() uint64 {
+	total := uint64(0)
+	for _, c := range h.Counts {
+		total += c
+	}
+	return total
+}
+
+// Quantile estimates the q-th quantile (0-1) as the upper boundary of the
+// bucket it falls into, or that bucket's lower boundary if the upper one
+// is infinite. It returns 0 for an empty histogram.
+func (h *Histogram) Quantile(q float64) float64 {
+	if h == nil {
+		return 0
+	}
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	seen := uint64(0)
+	for i, c := range h.Counts {
+		seen += c
+		if seen >= max(rank, 1) {
+			upper := float64(h.Buckets[i+1])
+			if math.IsInf(upper, 1) {
+				return float64(h.Buckets[i])
+			}
+			return upper
+		}
+	}
+	return float64(h.Buckets[len(h.Buckets)-1])
+}
@@ -0,0 +1,150 @@
+package agentmetrics
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// maxSamples bounds the timeline of long runs. When it fills up, every
+// other sample is dropped and the interval doubles.
+const maxSamples = 1024
+
+// Sample is one point of an agent's timeline
+type Sample struct {
+	Elapsed       time.Duration `json:"elapsed"`         // Since StartSampler
+	HeapInUse     uint64        `json:"heap_in_use"`     // Bytes in in-use heap spans, as MemStats.HeapInuse
+	HeapGoal      uint64        `json:"heap_goal"`       // Heap size at which the next GC starts
+	RSS           uint64        `json:"rss"`             // Resident set size, 0 where unsupported
+	Goroutines    int           `json:"goroutines"`      // Live goroutines
+	NumGC         uint64        `json:"num_gc"`          // Completed GC cycles
+	GCCPUFraction float64       `json:"gc_cpu_fraction"` // Since start, as of the last completed cycle
+}
+
+// Sampler records a Sample periodically in the background. It reads
+// runtime/metrics rather than runtime.ReadMemStats, which stops the world.
+type Sampler struct {
+	start time.Time
+	stop  chan struct{}
+	done  chan struct{}
+
+	mu       sync.Mutex
+	interval time.Duration
+	samples  []Sample
+}
+
+var samplerMetrics = []string{
+	"/memory/classes/heap/objects:bytes",
+	"/memory/classes/heap/unused:bytes",
+	MetricHeapGoal,
+	"/gc/cycles/total:gc-cycles",
+	MetricGCCPU,
+	MetricTotalCPU,
+}
+
+// StartSampler takes a first sample right away and then one every
+// interval until Stop is called
+func StartSampler(interval time.Duration) *Sampler {
+	s := &Sampler{
+		start:    time.Now(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		interval: interval,
+	}
+	s.record()
+	go s.run()
+	return s
+}
+
+func (s *Sampler) run() {
+	defer close(s.done)
+	interval := s.interval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.record()
+			s.mu.Lock()
+			if s.interval != interval {
+				interval = s.interval
+				ticker.Reset(interval)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// record appends a sample, halving the timeline when it is full
+func (s *Sampler) record() {
+	sample := readSample()
+	sample.Elapsed = time.Since(s.start)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.samples) == maxSamples {
+		kept := s.samples[:0]
+		for i := 0; i < len(s.samples); i += 2 {
+			kept = append(kept, s.samples[i])
+		}
+		s.samples = kept
+		s.interval *= 2
+	}
+	s.samples = append(s.samples, sample)
+}
+
+// Stop takes a final sample, stops the sampler and returns the timeline.
+// It returns nil for a nil Sampler, so callers can sample optionally.
+func (s *Sampler) Stop() []Sample {
+	if s == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.done
+	s.record()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.samples
+}
+
+func readSample() Sample {
+	samples := make([]metrics.Sample, len(samplerMetrics))
+	for i, name := range samplerMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+
+	values := map[string]metrics.Value{}
+	for _, m := range samples {
+		values[m.Name] = m.Value
+	}
+	uint64Value := func(name string) uint64 {
+		if v := values[name]; v.Kind() == metrics.KindUint64 {
+			return v.Uint64()
+		}
+		return 0
+	}
+	float64Value := func(name string) float64 {
+		if v := values[name]; v.Kind() == metrics.KindFloat64 {
+			return v.Float64()
+		}
+		return 0
+	}
+
+	sample := Sample{
+		HeapInUse:  uint64Value("/memory/classes/heap/objects:bytes") + uint64Value("/memory/classes/heap/unused:bytes"),
+		HeapGoal:   uint64Value(MetricHeapGoal),
+		RSS:        readRSS(),
+		Goroutines: runtime.NumGoroutine(),
+		NumGC:      uint64Value("/gc/cycles/total:gc-cycles"),
+	}
+	// The CPU classes are only brought up to date at the end of each cycle
+	if total := float64Value(MetricTotalCPU); total > 0 {
+		sample.GCCPUFraction = float64Value(MetricGCCPU) / total
+	}
+	return sample
+}
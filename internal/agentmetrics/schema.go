@@ -0,0 +1,34 @@
+package agentmetrics
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/natalie/go-flags-eval/internal/jsonschema"
+)
+
+// SchemaVersion is the version of the metrics file layout. Adding fields
+// does not change it, since readers ignore fields they do not know; it is
+// raised only when existing fields change name, type or meaning.
+const SchemaVersion = 1
+
+// Schema returns the JSON Schema of metrics files
+var Schema = sync.OnceValue(func() *jsonschema.Schema {
+	r := NewSchemaReflector(map[reflect.Type][]string{
+		reflect.TypeOf(Metrics{}): {"schema_version", "duration"},
+	})
+	return r.Reflect(reflect.TypeOf(Metrics{}), "Agent metrics")
+})
+
+// NewSchemaReflector returns a Reflector for documents that contain
+// metrics, which knows how Bound is encoded. required lists the required
+// properties by struct type.
+func NewSchemaReflector(required map[reflect.Type][]string) *jsonschema.Reflector {
+	return &jsonschema.Reflector{
+		Overrides: map[reflect.Type]*jsonschema.Schema{
+			// Finite bounds are numbers, infinite ones "+Inf"/"-Inf"
+			reflect.TypeOf(Bound(0)): {Type: jsonschema.Types{"number", "string"}},
+		},
+		Required: required,
+	}
+}
@@ -0,0 +1,43 @@
+package agentmetrics
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadFromFileBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		buckets string
+		wantErr string // Empty when the file is valid
+	}{
+		{"finite and infinite", `["-Inf", 0, 0.5, "+Inf"]`, ""},
+		{"boolean", `[0, true]`, "/runtime/histograms/~1sched~1latencies:seconds/buckets/1: expected number or string, got boolean"},
+		{"null", `[null]`, "buckets/0: expected number or string, got null"},
+	}
+	for _, tt := range tests {
+		filename := filepath.Join(t.TempDir(), "metrics.json")
+		data := `{"schema_version": 1, "duration": 1000, "runtime": {"histograms": {"/sched/latencies:seconds": {"counts": [1, 2, 3], "buckets": ` + tt.buckets + `}}}}`
+		if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFromFile(filename)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: ReadFromFile error = %v, want one containing %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: ReadFromFile: %v", tt.name, err)
+			continue
+		}
+		b := m.Runtime.Histograms[MetricSchedLatency].Buckets
+		if len(b) != 4 || !math.IsInf(float64(b[0]), -1) || b[2] != 0.5 || !math.IsInf(float64(b[3]), 1) {
+			t.Errorf("%s: buckets = %v, want [-Inf 0 0.5 +Inf]", tt.name, b)
+		}
+	}
+}
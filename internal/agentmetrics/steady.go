@@ -0,0 +1,82 @@
+package agentmetrics
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/stats"
+)
+
+// SteadyState describes a run that looped its workload for a fixed time
+// instead of running it once
+type SteadyState struct {
+	Warmup      time.Duration `json:"warmup"`
+	Window      time.Duration `json:"window"` // Measured wall time after warmup
+	Iterations  int           `json:"iterations"`
+	OpsPerSec   float64       `json:"ops_per_sec"`
+	LatencyP50  time.Duration `json:"latency_p50"` // Per iteration
+	LatencyP90  time.Duration `json:"latency_p90"`
+	LatencyP99  time.Duration `json:"latency_p99"`
+	LatencyP999 time.Duration `json:"latency_p999"`
+	LatencyMax  time.Duration `json:"latency_max"`
+}
+
+// RunSteadyState calls iteration until warmup has passed, then keeps
+// calling it for duration while timing every call. Allocation and GC
+// figures of the returned metrics cover the measured window only, so the
+// warmup's heap growth does not count against the steady state.
+func RunSteadyState(warmup, duration time.Duration, iteration func()) *Metrics {
+	for start := time.Now(); time.Since(start) < warmup; {
+		iteration()
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	latencies := []float64{}
+	start := time.Now()
+	for time.Since(start) < duration {
+		t := time.Now()
+		iteration()
+		latencies = append(latencies, float64(time.Since(t)))
+	}
+	window := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	percentile := func(p float64) time.Duration {
+		return time.Duration(stats.Percentile(latencies, p))
+	}
+	return &Metrics{
+		Duration:        window,
+		MemoryAllocated: after.TotalAlloc - before.TotalAlloc,
+		HeapAllocated:   after.HeapAlloc,
+		NumGC:           after.NumGC - before.NumGC,
+		PauseTimeNs:     after.PauseTotalNs - before.PauseTotalNs,
+		Goroutines:      runtime.NumGoroutine(),
+		SteadyState: &SteadyState{
+			Warmup:      warmup,
+			Window:      window,
+			Iterations:  len(latencies),
+			OpsPerSec:   float64(len(latencies)) / window.Seconds(),
+			LatencyP50:  percentile(50),
+			LatencyP90:  percentile(90),
+			LatencyP99:  percentile(99),
+			LatencyP999: percentile(99.9),
+			LatencyMax:  percentile(100),
+		},
+	}
+}
+
+// Print writes the steady-state block of an agent's results
+func (s *SteadyState) Print() {
+	fmt.Printf("Warmup: %v\n", s.Warmup)
+	fmt.Printf("Window: %v\n", s.Window.Round(time.Millisecond))
+	fmt.Printf("Iterations: %d\n", s.Iterations)
+	fmt.Printf("Throughput: %.2f ops/sec\n", s.OpsPerSec)
+	fmt.Printf("Latency p50/p90/p99/p999: %v / %v / %v / %v\n",
+		s.LatencyP50.Round(time.Microsecond),
+		s.LatencyP90.Round(time.Microsecond),
+		s.LatencyP99.Round(time.Microsecond),
+		s.LatencyP999.Round(time.Microsecond))
+	fmt.Printf("Latency max: %v\n", s.LatencyMax.Round(time.Microsecond))
+}
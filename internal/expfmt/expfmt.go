@@ -0,0 +1,229 @@
+// Package expfmt writes metrics in the Prometheus text exposition format
+// (version 0.0.4), which node_exporter's textfile collector reads, and in
+// OpenMetrics 1.0, which Prometheus prefers when scraping.
+package expfmt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Format is an exposition format
+type Format int
+
+const (
+	Text        Format = iota // Prometheus text format 0.0.4
+	OpenMetrics               // OpenMetrics 1.0 text format
+)
+
+// ContentType returns the media type of the format for HTTP responses
+func (f Format) ContentType() string {
+	if f == OpenMetrics {
+		return "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	}
+	return "text/plain; version=0.0.4; charset=utf-8"
+}
+
+// ParseFormat parses "prometheus" or "openmetrics"
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "prometheus":
+		return Text, nil
+	case "openmetrics":
+		return OpenMetrics, nil
+	}
+	return Text, fmt.Errorf("unknown format %q (want prometheus or openmetrics)", s)
+}
+
+// Negotiate picks the format for a scrape from its Accept header:
+// OpenMetrics when the scraper asks for it, the text format otherwise
+func Negotiate(accept string) Format {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		if strings.TrimSpace(mediaType) == "application/openmetrics-text" {
+			return OpenMetrics
+		}
+	}
+	return Text
+}
+
+// Type is the type of a metric family
+type Type string
+
+const (
+	Counter Type = "counter" // Samples get the _total suffix
+	Gauge   Type = "gauge"
+	Summary Type = "summary" // Quantile samples plus optional _count and _sum
+	Info    Type = "info"    // Value 1, samples get the _info suffix
+)
+
+// Label is a label name and value
+type Label struct {
+	Name, Value string
+}
+
+// Sample is one series of a family. Suffix is "_count" or "_sum" for
+// those series of a summary and empty otherwise.
+type Sample struct {
+	Suffix string
+	Labels []Label
+	Value  float64
+}
+
+// Family is a metric and all its series. Name is the family name, without
+// the _total or _info suffix of counters and info metrics.
+type Family struct {
+	Name    string
+	Type    Type
+	Help    string
+	Samples []Sample
+}
+
+// Families collects samples into families in the order the families are
+// first added. Both formats require all series of a family to be written
+// together.
+type Families struct {
+	list   []*Family
+	byName map[string]*Family
+}
+
+// Add adds a sample to the named family, creating it with typ and help on
+// first use
+func (fs *Families) Add(name string, typ Type, help string, labels []Label, value float64) {
+	fs.AddSample(name, typ, help, Sample{Labels: labels, Value: value})
+}
+
+// AddSample adds a sample with a suffix, such as a summary's _count
+func (fs *Families) AddSample(name string, typ Type, help string, s Sample) {
+	f := fs.family(name, typ, help)
+	f.Samples = append(f.Samples, s)
+}
+
+func (fs *Families) family(name string, typ Type, help string) *Family {
+	if fs.byName == nil {
+		fs.byName = map[string]*Family{}
+	}
+	f, ok := fs.byName[name]
+	if !ok {
+		f = &Family{Name: name, Type: typ, Help: help}
+		fs.byName[name] = f
+		fs.list = append(fs.list, f)
+	}
+	return f
+}
+
+// List returns the families in the order they were added
+func (fs *Families) List() []*Family {
+	return fs.list
+}
+
+// Write writes the families in the given format. The OpenMetrics output
+// ends with the # EOF marker the format requires.
+func Write(w io.Writer, families []*Family, format Format) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		writeFamily(bw, f, format)
+	}
+	if format == OpenMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+func writeFamily(w *bufio.Writer, f *Family, format Format) {
+	// The text format has no info type and names counters by their
+	// series; OpenMetrics names every family without the suffix
+	name, typ := f.Name, f.Type
+	if format == Text {
+		switch typ {
+		case Counter:
+			name += "_total"
+		case Info:
+			name += "_info"
+			typ = Gauge
+		}
+	}
+	if f.Help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(f.Help, format))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+
+	for _, s := range f.Samples {
+		w.WriteString(f.Name)
+		switch {
+		case s.Suffix != "":
+			w.WriteString(s.Suffix)
+		case f.Type == Counter:
+			w.WriteString("_total")
+		case f.Type == Info:
+			w.WriteString("_info")
+		}
+		if len(s.Labels) > 0 {
+			w.WriteByte('{')
+			for i, l := range s.Labels {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				fmt.Fprintf(w, `%s="%s"`, l.Name, escapeLabel(l.Value))
+			}
+			w.WriteByte('}')
+		}
+		w.WriteByte(' ')
+		w.WriteString(formatValue(s.Value))
+		w.WriteByte('\n')
+	}
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper    = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	textHelpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// escapeHelp escapes a HELP text; OpenMetrics also escapes double quotes
+func escapeHelp(s string, format Format) string {
+	if format == OpenMetrics {
+		return labelEscaper.Replace(s)
+	}
+	return textHelpEscaper.Replace(s)
+}
+
+// SanitizeName turns s into a valid metric or label name by replacing
+// every character outside [a-zA-Z0-9_] with an underscore and prefixing a
+// leading digit with one
+func SanitizeName(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,150 @@
+package expfmt
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func testFamilies() []*Family {
+	fs := &Families{}
+	run := []Label{{Name: "task", Value: "refactor"}, {Name: "config", Value: "gc-off"}}
+	fs.Add("goflags_results", Info, "Where the results were taken", []Label{{Name: "hostname", Value: `build "7"`}}, 1)
+	fs.Add("go_gc_cycles_total_gc_cycles", Counter, "Count of all completed GC cycles.", run, 12)
+	fs.Add("goflags_run_duration_seconds", Gauge, "Wall time of the run\nin seconds", run, 0.25)
+	fs.Add("goflags_steady_latency_seconds", Summary, "Latency of single iterations", append(run, Label{Name: "quantile", Value: "0.5"}), 0.001)
+	fs.AddSample("goflags_steady_latency_seconds", Summary, "", Sample{Suffix: "_count", Labels: run, Value: 300})
+	// Later samples join the family added first, whatever their position
+	fs.Add("goflags_run_duration_seconds", Gauge, "", []Label{{Name: "task", Value: `C:\agents`}}, math.Inf(1))
+	return fs.List()
+}
+
+func TestWriteText(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, testFamilies(), Text); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP goflags_results_info Where the results were taken
+# TYPE goflags_results_info gauge
+goflags_results_info{hostname="build \"7\""} 1
+# HELP go_gc_cycles_total_gc_cycles_total Count of all completed GC cycles.
+# TYPE go_gc_cycles_total_gc_cycles_total counter
+go_gc_cycles_total_gc_cycles_total{task="refactor",config="gc-off"} 12
+# HELP goflags_run_duration_seconds Wall time of the run\nin seconds
+# TYPE goflags_run_duration_seconds gauge
+goflags_run_duration_seconds{task="refactor",config="gc-off"} 0.25
+goflags_run_duration_seconds{task="C:\\agents"} +Inf
+# HELP goflags_steady_latency_seconds Latency of single iterations
+# TYPE goflags_steady_latency_seconds summary
+goflags_steady_latency_seconds{task="refactor",config="gc-off",quantile="0.5"} 0.001
+goflags_steady_latency_seconds_count{task="refactor",config="gc-off"} 300
+`
+	if b.String() != want {
+		t.Errorf("Write(Text) =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, testFamilies(), OpenMetrics); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP goflags_results Where the results were taken
+# TYPE goflags_results info
+goflags_results_info{hostname="build \"7\""} 1
+# HELP go_gc_cycles_total_gc_cycles Count of all completed GC cycles.
+# TYPE go_gc_cycles_total_gc_cycles counter
+go_gc_cycles_total_gc_cycles_total{task="refactor",config="gc-off"} 12
+# HELP goflags_run_duration_seconds Wall time of the run\nin seconds
+# TYPE goflags_run_duration_seconds gauge
+goflags_run_duration_seconds{task="refactor",config="gc-off"} 0.25
+goflags_run_duration_seconds{task="C:\\agents"} +Inf
+# HELP goflags_steady_latency_seconds Latency of single iterations
+# TYPE goflags_steady_latency_seconds summary
+goflags_steady_latency_seconds{task="refactor",config="gc-off",quantile="0.5"} 0.001
+goflags_steady_latency_seconds_count{task="refactor",config="gc-off"} 300
+# EOF
+`
+	if b.String() != want {
+		t.Errorf("Write(OpenMetrics) =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestEscapeHelp(t *testing.T) {
+	help := `a "quoted" \ help` + "\n"
+	if got, want := escapeHelp(help, Text), `a "quoted" \\ help\n`; got != want {
+		t.Errorf("escapeHelp(Text) = %s, want %s", got, want)
+	}
+	if got, want := escapeHelp(help, OpenMetrics), `a \"quoted\" \\ help\n`; got != want {
+		t.Errorf("escapeHelp(OpenMetrics) = %s, want %s", got, want)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0"},
+		{1, "1"},
+		{0.125, "0.125"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+		{math.Inf(-1), "-Inf"},
+		{math.NaN(), "NaN"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.v); got != tt.want {
+			t.Errorf("formatValue(%v) = %s, want %s", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Format
+	}{
+		{"", Text},
+		{"text/plain;version=0.0.4;q=0.5,*/*;q=0.1", Text},
+		{"application/openmetrics-text;version=1.0.0;escaping=allow-utf-8;q=0.5,text/plain;version=0.0.4;q=0.4", OpenMetrics},
+		{"text/plain, application/openmetrics-text", OpenMetrics},
+		{"application/openmetrics-textual", Text},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.accept); got != tt.want {
+			t.Errorf("Negotiate(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("openmetrics"); err != nil || f != OpenMetrics {
+		t.Errorf("ParseFormat(openmetrics) = %v, %v", f, err)
+	}
+	if f, err := ParseFormat("prometheus"); err != nil || f != Text {
+		t.Errorf("ParseFormat(prometheus) = %v, %v", f, err)
+	}
+	if _, err := ParseFormat("json"); err == nil {
+		t.Errorf("ParseFormat(json) succeeded")
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"go_gc_heap_goal_bytes", "go_gc_heap_goal_bytes"},
+		{"go_gc/heap/goal_bytes", "go_gc_heap_goal_bytes"},
+		{"go_sched_gomaxprocs_threads", "go_sched_gomaxprocs_threads"},
+		{"cpu-seconds", "cpu_seconds"},
+		{"99th", "_99th"},
+		{"p99", "p99"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := SanitizeName(tt.in); got != tt.want {
+			t.Errorf("SanitizeName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
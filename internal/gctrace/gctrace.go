@@ -0,0 +1,243 @@
+// Package gctrace parses the per-cycle lines the Go runtime prints to
+// stderr when GODEBUG=gctrace=1 is set
+package gctrace
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Event is one garbage collection cycle as reported by gctrace
+type Event struct {
+	Cycle      int           `json:"cycle"`
+	At         time.Duration `json:"at"`          // Time since program start
+	CPUPercent float64       `json:"cpu_percent"` // Share of CPU used by GC since program start
+
+	// Wall-clock time of each phase
+	SweepTermClock time.Duration `json:"sweep_term_clock"` // Stop-the-world
+	MarkClock      time.Duration `json:"mark_clock"`       // Concurrent
+	MarkTermClock  time.Duration `json:"mark_term_clock"`  // Stop-the-world
+
+	// CPU time of each phase
+	SweepTermCPU      time.Duration `json:"sweep_term_cpu"`
+	MarkAssistCPU     time.Duration `json:"mark_assist_cpu"`
+	MarkBackgroundCPU time.Duration `json:"mark_background_cpu"`
+	MarkIdleCPU       time.Duration `json:"mark_idle_cpu"`
+	MarkTermCPU       time.Duration `json:"mark_term_cpu"`
+
+	// Heap sizes in bytes (gctrace reports whole MiB)
+	HeapBefore uint64 `json:"heap_before"` // At GC start
+	HeapAfter  uint64 `json:"heap_after"`  // At GC end
+	HeapLive   uint64 `json:"heap_live"`   // Marked live
+	HeapGoal   uint64 `json:"heap_goal"`
+	Stacks     uint64 `json:"stacks,omitempty"`
+	Globals    uint64 `json:"globals,omitempty"`
+
+	Procs  int  `json:"procs"`
+	Forced bool `json:"forced,omitempty"` // Triggered by runtime.GC or debug.FreeOSMemory
+
+	// LimitTriggered is inferred, not reported: the heap goal was well
+	// below what GOGC alone would have allowed, so GOMEMLIMIT drove it
+	LimitTriggered bool `json:"limit_triggered,omitempty"`
+}
+
+// STW returns the total stop-the-world wall-clock time of the cycle
+func (e Event) STW() time.Duration {
+	return e.SweepTermClock + e.MarkTermClock
+}
+
+const (
+	num = `([0-9.]+)`
+	mb  = `([0-9]+)`
+)
+
+// Stacks and globals were added to the line in Go 1.18
+var lineRE = regexp.MustCompile(`^gc ([0-9]+) @` + num + `s ([0-9]+)%: ` +
+	num + `\+` + num + `\+` + num + ` ms clock, ` +
+	num + `\+` + num + `/` + num + `/` + num + `\+` + num + ` ms cpu, ` +
+	mb + `->` + mb + `->` + mb + ` MB, ` + mb + ` MB goal, ` +
+	`(?:` + mb + ` MB stacks, ` + mb + ` MB globals, )?` +
+	`([0-9]+) P( \(forced\))?`)
+
+// ParseLine parses a single gctrace line. It reports false for any other
+// output, including the scavenger's lines.
+func ParseLine(line string) (Event, bool) {
+	m := lineRE.FindStringSubmatch(line)
+	if m == nil {
+		return Event{}, false
+	}
+
+	atoi := func(s string) int {
+		v, _ := strconv.Atoi(s)
+		return v
+	}
+	mib := func(s string) uint64 {
+		v, _ := strconv.ParseUint(s, 10, 64)
+		return v << 20
+	}
+	ms := func(s string) time.Duration {
+		v, _ := strconv.ParseFloat(s, 64)
+		return time.Duration(v * float64(time.Millisecond))
+	}
+	seconds, _ := strconv.ParseFloat(m[2], 64)
+	percent, _ := strconv.ParseFloat(m[3], 64)
+
+	return Event{
+		Cycle:             atoi(m[1]),
+		At:                time.Duration(seconds * float64(time.Second)),
+		CPUPercent:        percent,
+		SweepTermClock:    ms(m[4]),
+		MarkClock:         ms(m[5]),
+		MarkTermClock:     ms(m[6]),
+		SweepTermCPU:      ms(m[7]),
+		MarkAssistCPU:     ms(m[8]),
+		MarkBackgroundCPU: ms(m[9]),
+		MarkIdleCPU:       ms(m[10]),
+		MarkTermCPU:       ms(m[11]),
+		HeapBefore:        mib(m[12]),
+		HeapAfter:         mib(m[13]),
+		HeapLive:          mib(m[14]),
+		HeapGoal:          mib(m[15]),
+		Stacks:            mib(m[16]),
+		Globals:           mib(m[17]),
+		Procs:             atoi(m[18]),
+		Forced:            m[19] != "",
+	}, true
+}
+
+// Parse reads r to the end and returns every gctrace event in it
+func Parse(r io.Reader) ([]Event, error) {
+	events := []Event{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if e, ok := ParseLine(scanner.Text()); ok {
+			events = append(events, e)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// Collector is an io.Writer meant to sit on a child's stderr. It keeps
+// the gctrace lines as events and forwards all other output unchanged.
+type Collector struct {
+	forward io.Writer
+
+	mu      sync.Mutex
+	partial []byte
+	events  []Event
+}
+
+// NewCollector returns a Collector forwarding non-gctrace output to w,
+// which may be nil to discard it
+func NewCollector(w io.Writer) *Collector {
+	return &Collector{forward: w}
+}
+
+func (c *Collector) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.partial = append(c.partial, p...)
+	for {
+		i := bytes.IndexByte(c.partial, '\n')
+		if i < 0 {
+			break
+		}
+		c.handle(c.partial[:i+1])
+		c.partial = c.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Events flushes any unterminated final line and returns the events
+// collected so far
+func (c *Collector) Events() []Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.partial) > 0 {
+		c.handle(c.partial)
+		c.partial = nil
+	}
+	return append([]Event(nil), c.events...)
+}
+
+func (c *Collector) handle(line []byte) {
+	if e, ok := ParseLine(string(bytes.TrimRight(line, "\r\n"))); ok {
+		c.events = append(c.events, e)
+		return
+	}
+	if c.forward != nil {
+		c.forward.Write(line)
+	}
+}
+
+// InferLimitTriggered marks cycles whose heap goal was at least 10% below
+// the goal GOGC alone would have produced, which means the memory limit
+// was the binding constraint. gcPercent < 0 means GOGC=off; memLimit is
+// in bytes and 0 means no limit. Because gctrace rounds to whole MiB the
+// inference is unreliable for heaps of only a few MiB.
+func InferLimitTriggered(events []Event, gcPercent int, memLimit uint64) {
+	if memLimit == 0 {
+		return
+	}
+	for i := range events {
+		if gcPercent < 0 {
+			// With GOGC=off every cycle is driven by the limit
+			events[i].LimitTriggered = !events[i].Forced
+			continue
+		}
+		if i == 0 {
+			continue
+		}
+		prev := events[i-1]
+		gogcGoal := prev.HeapLive + (prev.HeapLive+prev.Stacks+prev.Globals)*uint64(gcPercent)/100
+		if minGoal := uint64(4<<20) * uint64(gcPercent) / 100; gogcGoal < minGoal {
+			gogcGoal = minGoal
+		}
+		events[i].LimitTriggered = !events[i].Forced && events[i].HeapGoal+(1<<20) < gogcGoal*9/10
+	}
+}
+
+// Summary condenses a trace into the numbers worth comparing across runs
+type Summary struct {
+	Cycles         int           `json:"cycles"`
+	Forced         int           `json:"forced"`
+	LimitTriggered int           `json:"limit_triggered"`
+	TotalSTW       time.Duration `json:"total_stw"`
+	MaxSTW         time.Duration `json:"max_stw"`
+	PeakHeapGoal   uint64        `json:"peak_heap_goal"`
+	PeakHeapBefore uint64        `json:"peak_heap_before"`
+	FinalCPU       float64       `json:"final_cpu_percent"` // GC CPU share at the last cycle
+}
+
+// Summarize condenses events into a Summary
+func Summarize(events []Event) Summary {
+	s := Summary{Cycles: len(events)}
+	for _, e := range events {
+		if e.Forced {
+			s.Forced++
+		}
+		if e.LimitTriggered {
+			s.LimitTriggered++
+		}
+		stw := e.STW()
+		s.TotalSTW += stw
+		if stw > s.MaxSTW {
+			s.MaxSTW = stw
+		}
+		if e.HeapGoal > s.PeakHeapGoal {
+			s.PeakHeapGoal = e.HeapGoal
+		}
+		if e.HeapBefore > s.PeakHeapBefore {
+			s.PeakHeapBefore = e.HeapBefore
+		}
+		s.FinalCPU = e.CPUPercent
+	}
+	return s
+}
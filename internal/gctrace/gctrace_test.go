@@ -0,0 +1,216 @@
+package gctrace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const mib = 1 << 20
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want Event
+	}{
+		{
+			name: "go1.18+",
+			line: "gc 4 @0.003s 10%: 0.009+0.19+0.002 ms clock, 0.009+0.11/0/0+0.002 ms cpu, 17->19->13 MB, 20 MB goal, 0 MB stacks, 0 MB globals, 1 P",
+			want: Event{
+				Cycle: 4, At: 3 * time.Millisecond, CPUPercent: 10,
+				SweepTermClock: 9 * time.Microsecond, MarkClock: 190 * time.Microsecond, MarkTermClock: 2 * time.Microsecond,
+				SweepTermCPU: 9 * time.Microsecond, MarkAssistCPU: 110 * time.Microsecond, MarkTermCPU: 2 * time.Microsecond,
+				HeapBefore: 17 * mib, HeapAfter: 19 * mib, HeapLive: 13 * mib, HeapGoal: 20 * mib,
+				Procs: 1,
+			},
+		},
+		{
+			name: "forced",
+			line: "gc 5 @0.004s 8%: 0.006+0.11+0.001 ms clock, 0.006+0/0.025/0.083+0.001 ms cpu, 14->14->7 MB, 26 MB goal, 0 MB stacks, 0 MB globals, 1 P (forced)",
+			want: Event{
+				Cycle: 5, At: 4 * time.Millisecond, CPUPercent: 8,
+				SweepTermClock: 6 * time.Microsecond, MarkClock: 110 * time.Microsecond, MarkTermClock: 1 * time.Microsecond,
+				SweepTermCPU: 6 * time.Microsecond, MarkBackgroundCPU: 25 * time.Microsecond, MarkIdleCPU: 83 * time.Microsecond, MarkTermCPU: 1 * time.Microsecond,
+				HeapBefore: 14 * mib, HeapAfter: 14 * mib, HeapLive: 7 * mib, HeapGoal: 26 * mib,
+				Procs: 1, Forced: true,
+			},
+		},
+		{
+			name: "stacks and globals",
+			line: "gc 112 @12.345s 3%: 0.050+4.2+0.075 ms clock, 0.40+1.1/8.3/15+0.60 ms cpu, 180->190->100 MB, 200 MB goal, 2 MB stacks, 1 MB globals, 8 P",
+			want: Event{
+				Cycle: 112, At: 12345 * time.Millisecond, CPUPercent: 3,
+				SweepTermClock: 50 * time.Microsecond, MarkClock: 4200 * time.Microsecond, MarkTermClock: 75 * time.Microsecond,
+				SweepTermCPU: 400 * time.Microsecond, MarkAssistCPU: 1100 * time.Microsecond, MarkBackgroundCPU: 8300 * time.Microsecond,
+				MarkIdleCPU: 15 * time.Millisecond, MarkTermCPU: 600 * time.Microsecond,
+				HeapBefore: 180 * mib, HeapAfter: 190 * mib, HeapLive: 100 * mib, HeapGoal: 200 * mib,
+				Stacks: 2 * mib, Globals: 1 * mib,
+				Procs: 8,
+			},
+		},
+		{
+			name: "before go1.18",
+			line: "gc 1 @0.012s 2%: 0.026+0.39+0.10 ms clock, 0.21+0.12/0.46/0.93+0.82 ms cpu, 4->4->0 MB, 5 MB goal, 8 P",
+			want: Event{
+				Cycle: 1, At: 12 * time.Millisecond, CPUPercent: 2,
+				SweepTermClock: 26 * time.Microsecond, MarkClock: 390 * time.Microsecond, MarkTermClock: 100 * time.Microsecond,
+				SweepTermCPU: 210 * time.Microsecond, MarkAssistCPU: 120 * time.Microsecond, MarkBackgroundCPU: 460 * time.Microsecond,
+				MarkIdleCPU: 930 * time.Microsecond, MarkTermCPU: 820 * time.Microsecond,
+				HeapBefore: 4 * mib, HeapAfter: 4 * mib, HeapGoal: 5 * mib,
+				Procs: 8,
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, ok := ParseLine(tt.line)
+		if !ok {
+			t.Errorf("%s: ParseLine(%q) did not match", tt.name, tt.line)
+			continue
+		}
+		// Float milliseconds do not convert to exact durations
+		for _, d := range []*time.Duration{
+			&got.At, &got.SweepTermClock, &got.MarkClock, &got.MarkTermClock, &got.SweepTermCPU,
+			&got.MarkAssistCPU, &got.MarkBackgroundCPU, &got.MarkIdleCPU, &got.MarkTermCPU,
+		} {
+			*d = d.Round(time.Microsecond)
+		}
+		if got != tt.want {
+			t.Errorf("%s: ParseLine =\n%+v\nwant\n%+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseLineIgnoresOtherOutput(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"Found 42 Go files to parse",
+		"scvg: 0 MB released",
+		"scvg: inuse: 3, idle: 0, sys: 3, released: 0, consumed: 3 (MB)",
+		"scav 0 KiB work (bg), 0 KiB work (eager), 1024 KiB total, 99% util",
+		"gc 1 @0.000s 25%: 0.006+0.14+0.004 ms clock",
+		"GC forced",
+		"panic: runtime error: index out of range [3] with length 3",
+		"log: gc 7 started",
+	} {
+		if e, ok := ParseLine(line); ok {
+			t.Errorf("ParseLine(%q) = %+v, want no match", line, e)
+		}
+	}
+}
+
+func TestCollector(t *testing.T) {
+	var forwarded strings.Builder
+	c := NewCollector(&forwarded)
+
+	input := "Refactor Agent\n" +
+		"gc 1 @0.000s 25%: 0.006+0.14+0.004 ms clock, 0.006+0.13/0/0+0.004 ms cpu, 3->3->3 MB, 4 MB goal, 0 MB stacks, 0 MB globals, 1 P\n" +
+		"scvg: 0 MB released\n" +
+		"gc 2 @0.000s 25%: 0.005+0.076+0.001 ms clock, 0.005+0.071/0/0+0.001 ms cpu, 7->7->7 MB, 7 MB goal, 0 MB stacks, 0 MB globals, 1 P\r\n" +
+		"gc 3 @0.004s 8%: 0.006+0.11+0.001 ms clock, 0.006+0/0.025/0.083+0.001 ms cpu, 14->14->7 MB, 26 MB goal, 0 MB stacks, 0 MB globals, 1 P (forced)"
+	// Lines arrive split across writes at arbitrary points
+	for i := 0; i < len(input); i += 7 {
+		end := min(i+7, len(input))
+		if n, err := c.Write([]byte(input[i:end])); n != end-i || err != nil {
+			t.Fatalf("Write = %d, %v", n, err)
+		}
+	}
+
+	events := c.Events()
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	for i, e := range events {
+		if e.Cycle != i+1 {
+			t.Errorf("event %d has cycle %d", i, e.Cycle)
+		}
+	}
+	if !events[2].Forced {
+		t.Errorf("unterminated final line not parsed as forced cycle: %+v", events[2])
+	}
+	if want := "Refactor Agent\nscvg: 0 MB released\n"; forwarded.String() != want {
+		t.Errorf("forwarded %q, want %q", forwarded.String(), want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	input := `Searching...
+gc 1 @0.000s 21%: 0.006+0.14+0.003 ms clock, 0.006+0.12/0/0+0.003 ms cpu, 4->5->4 MB, 5 MB goal, 0 MB stacks, 0 MB globals, 1 P
+matches: 12
+gc 2 @0.000s 23%: 0.005+0.072+0.001 ms clock, 0.005+0.071/0/0+0.001 ms cpu, 4->4->4 MB, 4 MB goal, 0 MB stacks, 0 MB globals, 1 P
+`
+	events, err := Parse(strings.NewReader(input))
+	if err != nil || len(events) != 2 {
+		t.Fatalf("Parse = %d events, %v, want 2", len(events), err)
+	}
+}
+
+func mustParse(t *testing.T, lines ...string) []Event {
+	t.Helper()
+	events := []Event{}
+	for _, line := range lines {
+		e, ok := ParseLine(line)
+		if !ok {
+			t.Fatalf("ParseLine(%q) did not match", line)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestInferLimitTriggered(t *testing.T) {
+	// With GOGC=100 and 100 MB live the goal would be about 203 MB;
+	// cycle 8's 150 MB goal means the memory limit set the pace
+	lines := []string{
+		"gc 7 @1.200s 3%: 0.050+4.2+0.075 ms clock, 0.40+1.1/8.3/15+0.60 ms cpu, 180->190->100 MB, 200 MB goal, 2 MB stacks, 1 MB globals, 8 P",
+		"gc 8 @1.300s 4%: 0.050+4.2+0.075 ms clock, 0.40+1.1/8.3/15+0.60 ms cpu, 140->150->105 MB, 150 MB goal, 2 MB stacks, 1 MB globals, 8 P",
+		"gc 9 @1.400s 4%: 0.050+4.2+0.075 ms clock, 0.40+1.1/8.3/15+0.60 ms cpu, 200->205->100 MB, 210 MB goal, 2 MB stacks, 1 MB globals, 8 P",
+		"gc 10 @1.500s 4%: 0.050+4.2+0.075 ms clock, 0.40+1.1/8.3/15+0.60 ms cpu, 90->90->60 MB, 120 MB goal, 2 MB stacks, 1 MB globals, 8 P (forced)",
+	}
+
+	tests := []struct {
+		name      string
+		gcPercent int
+		memLimit  uint64
+		want      []bool
+	}{
+		{"no limit", 100, 0, []bool{false, false, false, false}},
+		{"GOGC=100 with limit", 100, 160 * mib, []bool{false, true, false, false}},
+		{"GOGC=off with limit", -1, 160 * mib, []bool{true, true, true, false}},
+	}
+	for _, tt := range tests {
+		events := mustParse(t, lines...)
+		InferLimitTriggered(events, tt.gcPercent, tt.memLimit)
+		for i, e := range events {
+			if e.LimitTriggered != tt.want[i] {
+				t.Errorf("%s: cycle %d LimitTriggered = %v, want %v", tt.name, e.Cycle, e.LimitTriggered, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	events := mustParse(t,
+		"gc 1 @0.000s 25%: 0.006+0.14+0.004 ms clock, 0.006+0.13/0/0+0.004 ms cpu, 3->3->3 MB, 4 MB goal, 0 MB stacks, 0 MB globals, 1 P",
+		"gc 2 @0.003s 10%: 0.009+0.19+0.020 ms clock, 0.009+0.11/0/0+0.002 ms cpu, 17->19->13 MB, 20 MB goal, 0 MB stacks, 0 MB globals, 1 P",
+		"gc 3 @0.004s 8%: 0.006+0.11+0.001 ms clock, 0.006+0/0.025/0.083+0.001 ms cpu, 14->14->7 MB, 26 MB goal, 0 MB stacks, 0 MB globals, 1 P (forced)",
+	)
+	events[1].LimitTriggered = true
+
+	s := Summarize(events)
+	if s.Cycles != 3 || s.Forced != 1 || s.LimitTriggered != 1 {
+		t.Errorf("counts = %d cycles, %d forced, %d limit, want 3, 1, 1", s.Cycles, s.Forced, s.LimitTriggered)
+	}
+	if s.PeakHeapGoal != 26*mib || s.PeakHeapBefore != 17*mib {
+		t.Errorf("peaks = goal %d, before %d", s.PeakHeapGoal, s.PeakHeapBefore)
+	}
+	if got := s.MaxSTW.Round(time.Microsecond); got != 29*time.Microsecond {
+		t.Errorf("MaxSTW = %v, want 29µs", got)
+	}
+	if got := s.TotalSTW.Round(time.Microsecond); got != 46*time.Microsecond {
+		t.Errorf("TotalSTW = %v, want 46µs", got)
+	}
+	if s.FinalCPU != 8 {
+		t.Errorf("FinalCPU = %v, want 8", s.FinalCPU)
+	}
+}
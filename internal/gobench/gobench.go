@@ -0,0 +1,80 @@
+// Package gobench parses the result lines printed by go test -bench
+package gobench
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Result is one benchmark result line, e.g.
+//
+//	BenchmarkParse-8   	  120000	      9876 ns/op	    4096 B/op	      12 allocs/op
+type Result struct {
+	Name        string             `json:"name"` // Without the -GOMAXPROCS suffix
+	Procs       int                `json:"procs"`
+	Iterations  int64              `json:"iterations"`
+	NsPerOp     float64            `json:"ns_per_op"`
+	BytesPerOp  float64            `json:"bytes_per_op,omitempty"`  // With -benchmem or b.ReportAllocs
+	AllocsPerOp float64            `json:"allocs_per_op,omitempty"` // With -benchmem or b.ReportAllocs
+	MBPerSec    float64            `json:"mb_per_sec,omitempty"`    // With b.SetBytes
+	Metrics     map[string]float64 `json:"metrics,omitempty"`       // b.ReportMetric values by unit
+}
+
+// ParseLine parses a single benchmark result line. It reports false for
+// any other output, including the goos/goarch/pkg header and PASS.
+func ParseLine(line string) (Result, bool) {
+	fields := strings.Fields(line)
+	// Name, iterations and at least one value/unit pair
+	if len(fields) < 4 || len(fields)%2 != 0 || !strings.HasPrefix(fields[0], "Benchmark") {
+		return Result{}, false
+	}
+
+	iterations, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return Result{}, false
+	}
+
+	r := Result{Name: fields[0], Procs: 1, Iterations: iterations}
+	if i := strings.LastIndexByte(r.Name, '-'); i > 0 {
+		if procs, err := strconv.Atoi(r.Name[i+1:]); err == nil {
+			r.Name, r.Procs = r.Name[:i], procs
+		}
+	}
+
+	for i := 2; i < len(fields); i += 2 {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return Result{}, false
+		}
+		switch unit := fields[i+1]; unit {
+		case "ns/op":
+			r.NsPerOp = value
+		case "B/op":
+			r.BytesPerOp = value
+		case "allocs/op":
+			r.AllocsPerOp = value
+		case "MB/s":
+			r.MBPerSec = value
+		default:
+			if r.Metrics == nil {
+				r.Metrics = map[string]float64{}
+			}
+			r.Metrics[unit] = value
+		}
+	}
+	return r, true
+}
+
+// Parse reads r to the end and returns every benchmark result in it
+func Parse(r io.Reader) ([]Result, error) {
+	results := []Result{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if result, ok := ParseLine(scanner.Text()); ok {
+			results = append(results, result)
+		}
+	}
+	return results, scanner.Err()
+}
@@ -0,0 +1,96 @@
+package gobench
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want Result
+	}{
+		{
+			line: "BenchmarkParse-8   	  120000	      9876 ns/op	    4096 B/op	      12 allocs/op",
+			want: Result{Name: "BenchmarkParse", Procs: 8, Iterations: 120000, NsPerOp: 9876, BytesPerOp: 4096, AllocsPerOp: 12},
+		},
+		{
+			// Without -GOMAXPROCS suffix, as with GOMAXPROCS=1
+			line: "BenchmarkDecode	 1000000	      1052 ns/op	 486.58 MB/s",
+			want: Result{Name: "BenchmarkDecode", Procs: 1, Iterations: 1000000, NsPerOp: 1052, MBPerSec: 486.58},
+		},
+		{
+			// Sub-benchmark names may contain dashes of their own
+			line: "BenchmarkSort/n-1000-4         	   50000	     30210 ns/op",
+			want: Result{Name: "BenchmarkSort/n-1000", Procs: 4, Iterations: 50000, NsPerOp: 30210},
+		},
+		{
+			line: "BenchmarkSort/size-large         	   50000	     30210 ns/op",
+			want: Result{Name: "BenchmarkSort/size-large", Procs: 1, Iterations: 50000, NsPerOp: 30210},
+		},
+		{
+			// b.ReportMetric units
+			line: "BenchmarkCache-2   	    2000	    512000 ns/op	         0.9500 hit-ratio	       3.000 evictions/op",
+			want: Result{
+				Name: "BenchmarkCache", Procs: 2, Iterations: 2000, NsPerOp: 512000,
+				Metrics: map[string]float64{"hit-ratio": 0.95, "evictions/op": 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, ok := ParseLine(tt.line)
+		if !ok {
+			t.Errorf("ParseLine(%q) did not match", tt.line)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLine(%q) =\n%+v\nwant\n%+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseLineIgnoresOtherOutput(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"goos: linux",
+		"goarch: amd64",
+		"pkg: github.com/natalie/go-flags-eval/internal/stats",
+		"cpu: AMD EPYC 7B13",
+		"PASS",
+		"ok  	github.com/natalie/go-flags-eval/internal/stats	1.234s",
+		"BenchmarkParse-8",
+		"BenchmarkParse-8   	--- FAIL: BenchmarkParse-8",
+		"BenchmarkParse-8   	  120000	      9876 ns/op	    4096",
+		"BenchmarkParse-8   	    many	      9876 ns/op",
+		"BenchmarkParse-8   	  120000	      fast ns/op",
+		"Benchmarking took 3s total",
+	} {
+		if r, ok := ParseLine(line); ok {
+			t.Errorf("ParseLine(%q) = %+v, want no match", line, r)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	output := `goos: linux
+goarch: amd64
+pkg: example.com/parser
+BenchmarkParse-8   	  120000	      9876 ns/op	    4096 B/op	      12 allocs/op
+BenchmarkParse-8   	  118000	      9911 ns/op	    4096 B/op	      12 allocs/op
+BenchmarkLex-8     	 2000000	       612 ns/op	       0 B/op	       0 allocs/op
+PASS
+ok  	example.com/parser	4.512s
+`
+	results, err := Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, r := range results {
+		names = append(names, r.Name)
+	}
+	if want := []string{"BenchmarkParse", "BenchmarkParse", "BenchmarkLex"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Parse returned %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,223 @@
+// Package jsonschema derives JSON Schema (draft 2020-12) documents from Go
+// types and validates decoded JSON against them. It covers the subset of
+// the specification that encoding/json output needs: types, properties,
+// required, items, additionalProperties, minimum, anyOf and local $refs.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document or subschema. The zero Schema accepts
+// any value.
+type Schema struct {
+	Draft       string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+
+	Type                 Types              `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+
+	Defs map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Types is the "type" keyword, written as a string when it holds one type
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = Types{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// Reflector derives schemas from Go types the way encoding/json encodes
+// them. Named struct types become $defs, so recursive types work.
+type Reflector struct {
+	// Overrides are the schemas of types with their own JSON encoding,
+	// which reflection cannot see
+	Overrides map[reflect.Type]*Schema
+	// Required lists the properties a struct type must have. Every other
+	// property is optional, so that files written before it was added
+	// still validate.
+	Required map[reflect.Type][]string
+
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Reflect returns the schema of values of type t as a standalone document
+func (r *Reflector) Reflect(t reflect.Type, title string) *Schema {
+	r.defs = map[string]*Schema{}
+	r.names = map[reflect.Type]string{}
+	root := r.schemaOf(t)
+	if root.Ref != "" {
+		// Inline the root type rather than referring to it
+		name := strings.TrimPrefix(root.Ref, "#/$defs/")
+		root = r.defs[name]
+		delete(r.defs, name)
+		for _, def := range r.defs {
+			rewriteRef(def, "#/$defs/"+name, "#")
+		}
+		rewriteRef(root, "#/$defs/"+name, "#")
+	}
+	root.Draft = draft
+	root.Title = title
+	if len(r.defs) > 0 {
+		root.Defs = r.defs
+	}
+	return root
+}
+
+func (r *Reflector) schemaOf(t reflect.Type) *Schema {
+	if s, ok := r.Overrides[t]; ok {
+		return s
+	}
+	if t == timeType {
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(r.schemaOf(t.Elem()))
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: Types{"integer"}}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		zero := 0.0
+		return &Schema{Type: Types{"integer"}, Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is encoded as base64
+			return &Schema{Type: Types{"string", "null"}}
+		}
+		return &Schema{Type: Types{"array", "null"}, Items: r.schemaOf(t.Elem())}
+	case reflect.Array:
+		return &Schema{Type: Types{"array"}, Items: r.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: Types{"object", "null"}, AdditionalProperties: r.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return r.structRef(t)
+	}
+	// Interfaces and anything else: any value
+	return &Schema{}
+}
+
+// structRef adds the struct type to $defs on first use and refers to it
+func (r *Reflector) structRef(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return r.structSchema(t)
+	}
+	name, ok := r.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := r.defs[name]; taken {
+			name = strings.ReplaceAll(t.String(), ".", "_")
+		}
+		r.names[t] = name
+		r.defs[name] = &Schema{} // Placeholder for recursive references
+		r.defs[name] = r.structSchema(t)
+	}
+	return &Schema{Ref: "#/$defs/" + name}
+}
+
+func (r *Reflector) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: Types{"object"}, Properties: map[string]*Schema{}}
+	r.addFields(s, t)
+	s.Required = r.Required[t]
+	return s
+}
+
+// addFields adds the properties encoding/json writes for t's fields,
+// including those promoted from embedded structs
+func (r *Reflector) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			r.addFields(s, f.Type)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = r.schemaOf(f.Type)
+	}
+}
+
+// nullable also accepts null, as encoding/json writes for nil pointers
+func nullable(s *Schema) *Schema {
+	for _, t := range s.Type {
+		if t == "null" {
+			return s
+		}
+	}
+	if len(s.Type) > 0 && s.Ref == "" {
+		c := *s
+		c.Type = append(append(Types{}, s.Type...), "null")
+		return &c
+	}
+	return &Schema{AnyOf: []*Schema{s, {Type: Types{"null"}}}}
+}
+
+func rewriteRef(s *Schema, from, to string) {
+	if s == nil {
+		return
+	}
+	if s.Ref == from {
+		s.Ref = to
+	}
+	for _, p := range s.Properties {
+		rewriteRef(p, from, to)
+	}
+	rewriteRef(s.AdditionalProperties, from, to)
+	rewriteRef(s.Items, from, to)
+	for _, a := range s.AnyOf {
+		rewriteRef(a, from, to)
+	}
+}
+
+// MarshalIndent encodes the schema as an indented JSON document
+func (s *Schema) MarshalIndent() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,116 @@
+package jsonschema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type level float64
+
+type node struct {
+	Name     string         `json:"name"`
+	Weight   uint32         `json:"weight"`
+	Level    level          `json:"level"`
+	Parent   *node          `json:"parent,omitempty"`
+	Children []node         `json:"children"`
+	Labels   map[string]int `json:"labels,omitempty"`
+	Data     []byte         `json:"data,omitempty"`
+	Created  time.Time      `json:"created"`
+	Extra    any            `json:"extra,omitempty"`
+	Limit    *int           `json:"limit,omitempty"`
+	Pair     [2]float64     `json:"pair"`
+	Skipped  string         `json:"-"`
+	hidden   string
+	Embedded
+	Renamed Embedded `json:"renamed"`
+}
+
+type Embedded struct {
+	Note string
+}
+
+func testReflector() *Reflector {
+	return &Reflector{
+		// level is written as a number or as "+Inf"/"-Inf"
+		Overrides: map[reflect.Type]*Schema{
+			reflect.TypeOf(level(0)): {Type: Types{"number", "string"}},
+		},
+		Required: map[reflect.Type][]string{
+			reflect.TypeOf(node{}): {"name"},
+		},
+	}
+}
+
+func TestReflect(t *testing.T) {
+	s := testReflector().Reflect(reflect.TypeOf(node{}), "Node")
+	if s.Draft != draft || s.Title != "Node" {
+		t.Errorf("root has $schema %q and title %q", s.Draft, s.Title)
+	}
+	if _, ok := s.Defs["node"]; ok {
+		t.Errorf("root type is in $defs, want it inlined")
+	}
+	if !reflect.DeepEqual(s.Required, []string{"name"}) {
+		t.Errorf("required = %v, want [name]", s.Required)
+	}
+
+	var names []string
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	for _, name := range []string{"Skipped", "-", "hidden", "Embedded"} {
+		if _, ok := s.Properties[name]; ok {
+			t.Errorf("property %q present, want it left out", name)
+		}
+	}
+	if _, ok := s.Properties["Note"]; !ok {
+		t.Errorf("properties %v lack Note, promoted from the embedded struct", names)
+	}
+
+	zero := 0.0
+	tests := []struct {
+		property string
+		want     *Schema
+	}{
+		{"name", &Schema{Type: Types{"string"}}},
+		{"weight", &Schema{Type: Types{"integer"}, Minimum: &zero}},
+		{"level", &Schema{Type: Types{"number", "string"}}},
+		{"parent", &Schema{AnyOf: []*Schema{{Ref: "#"}, {Type: Types{"null"}}}}},
+		{"children", &Schema{Type: Types{"array", "null"}, Items: &Schema{Ref: "#"}}},
+		{"labels", &Schema{Type: Types{"object", "null"}, AdditionalProperties: &Schema{Type: Types{"integer"}}}},
+		{"data", &Schema{Type: Types{"string", "null"}}},
+		{"created", &Schema{Type: Types{"string"}, Format: "date-time"}},
+		{"extra", &Schema{}},
+		{"limit", &Schema{Type: Types{"integer", "null"}}},
+		{"pair", &Schema{Type: Types{"array"}, Items: &Schema{Type: Types{"number"}}}},
+		{"renamed", &Schema{Ref: "#/$defs/Embedded"}},
+	}
+	for _, tt := range tests {
+		if got := s.Properties[tt.property]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("property %q = %+v, want %+v", tt.property, got, tt.want)
+		}
+	}
+	if def := s.Defs["Embedded"]; def == nil || def.Properties["Note"] == nil {
+		t.Errorf("$defs/Embedded = %+v, want an object with Note", def)
+	}
+}
+
+func TestTypesJSON(t *testing.T) {
+	s := &Schema{Type: Types{"integer"}, Items: &Schema{Type: Types{"string", "null"}}}
+	data, err := s.MarshalIndent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); !strings.Contains(got, `"type": "integer"`) || !strings.Contains(got, `"type": [`) {
+		t.Errorf("MarshalIndent =\n%s\nwant a single type as a string and a union as an array", got)
+	}
+
+	var decoded Schema
+	if err := decoded.Type.UnmarshalJSON([]byte(`"integer"`)); err != nil || !reflect.DeepEqual(decoded.Type, Types{"integer"}) {
+		t.Errorf("UnmarshalJSON(string) = %v, %v", decoded.Type, err)
+	}
+	if err := decoded.Type.UnmarshalJSON([]byte(`["string", "null"]`)); err != nil || !reflect.DeepEqual(decoded.Type, Types{"string", "null"}) {
+		t.Errorf("UnmarshalJSON(array) = %v, %v", decoded.Type, err)
+	}
+}
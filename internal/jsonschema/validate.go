@@ -0,0 +1,235 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxErrors bounds the problems a ValidationError lists
+const maxErrors = 20
+
+// ValidationError lists where a document does not match its schema, as
+// JSON pointers followed by the problem
+type ValidationError struct {
+	Errors    []string
+	Truncated bool // More than maxErrors problems were found
+}
+
+func (e *ValidationError) Error() string {
+	msg := strings.Join(e.Errors, "; ")
+	if e.Truncated {
+		msg += "; ..."
+	}
+	return "schema validation failed: " + msg
+}
+
+// Decode decodes JSON the way Validate expects it, with numbers kept as
+// json.Number so that integers can be told from floats
+func Decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Validate checks a value produced by Decode against the schema and
+// returns a *ValidationError listing every mismatch
+func (s *Schema) Validate(v any) error {
+	vs := &validator{root: s}
+	vs.validate(s, v, "")
+	if len(vs.errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: vs.errors, Truncated: vs.truncated}
+}
+
+type validator struct {
+	root      *Schema
+	errors    []string
+	truncated bool
+}
+
+func (vs *validator) fail(path, format string, args ...any) {
+	if path == "" {
+		path = "/"
+	}
+	vs.add(path + ": " + fmt.Sprintf(format, args...))
+}
+
+func (vs *validator) add(msg string) {
+	if len(vs.errors) == maxErrors {
+		vs.truncated = true
+		return
+	}
+	vs.errors = append(vs.errors, msg)
+}
+
+func (vs *validator) resolve(ref string) *Schema {
+	if ref == "#" {
+		return vs.root
+	}
+	if name, ok := strings.CutPrefix(ref, "#/$defs/"); ok {
+		return vs.root.Defs[name]
+	}
+	return nil
+}
+
+func (vs *validator) validate(s *Schema, v any, path string) {
+	if s.Ref != "" {
+		target := vs.resolve(s.Ref)
+		if target == nil {
+			vs.fail(path, "unresolvable $ref %q", s.Ref)
+			return
+		}
+		vs.validate(target, v, path)
+	}
+
+	if len(s.AnyOf) > 0 {
+		matched := false
+		var failed []*validator
+		for _, alt := range s.AnyOf {
+			sub := &validator{root: vs.root}
+			sub.validate(alt, v, path)
+			if len(sub.errors) == 0 {
+				matched = true
+				break
+			}
+			if v == nil || !reflect.DeepEqual(alt.Type, Types{"null"}) {
+				failed = append(failed, sub)
+			}
+		}
+		switch {
+		case matched:
+		case len(failed) == 1:
+			// A nullable value that is not null: say why the value
+			// does not match rather than that null was not given
+			for _, msg := range failed[0].errors {
+				vs.add(msg)
+			}
+			vs.truncated = vs.truncated || failed[0].truncated
+		default:
+			vs.fail(path, "matches none of the allowed schemas")
+		}
+	}
+
+	if len(s.Type) > 0 && !typeMatches(s.Type, v) {
+		vs.fail(path, "expected %s, got %s", strings.Join(s.Type, " or "), typeName(v))
+		return
+	}
+
+	switch x := v.(type) {
+	case json.Number:
+		if s.Minimum != nil {
+			if f, err := x.Float64(); err == nil && f < *s.Minimum {
+				vs.fail(path, "%s is below the minimum of %g", x, *s.Minimum)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := x[name]; !ok {
+				vs.fail(path, "missing required property %q", name)
+			}
+		}
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := path + "/" + escapePointer(k)
+			if p, ok := s.Properties[k]; ok {
+				vs.validate(p, x[k], child)
+			} else if s.AdditionalProperties != nil {
+				vs.validate(s.AdditionalProperties, x[k], child)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range x {
+				vs.validate(s.Items, item, path+"/"+strconv.Itoa(i))
+			}
+		}
+	}
+}
+
+func typeMatches(types Types, v any) bool {
+	for _, t := range types {
+		switch t {
+		case "null":
+			if v == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := v.(json.Number); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := v.(json.Number); ok && isInteger(n) {
+				return true
+			}
+		case "object":
+			if _, ok := v.(map[string]any); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]any); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isInteger(n json.Number) bool {
+	if _, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return true
+	}
+	if _, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return true
+	}
+	f, err := n.Float64()
+	return err == nil && f == math.Trunc(f)
+}
+
+func typeName(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if isInteger(x) {
+			return "integer"
+		}
+		return "number"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// escapePointer escapes a property name for use in a JSON pointer
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
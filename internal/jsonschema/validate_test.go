@@ -0,0 +1,116 @@
+package jsonschema
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	s := testReflector().Reflect(reflect.TypeOf(node{}), "Node")
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string // Empty when the document is valid
+	}{
+		{"minimal", `{"name": "a"}`, ""},
+		{"unknown fields", `{"name": "a", "added_later": [1, "x"], "Note": "n"}`, ""},
+		{"missing required", `{"weight": 1}`, `/: missing required property "name"`},
+		{"not an object", `[]`, "/: expected object, got array"},
+		{"wrong type", `{"name": 1}`, "/name: expected string, got integer"},
+		{"float for integer", `{"name": "a", "weight": 1.5}`, "/weight: expected integer, got number"},
+		{"integral float", `{"name": "a", "weight": 2.0}`, ""},
+		{"below minimum", `{"name": "a", "weight": -1}`, "/weight: -1 is below the minimum of 0"},
+		{"union number", `{"name": "a", "level": 0.5}`, ""},
+		{"union string", `{"name": "a", "level": "+Inf"}`, ""},
+		{"union neither", `{"name": "a", "level": true}`, "/level: expected number or string, got boolean"},
+		{"nullable pointer", `{"name": "a", "limit": null, "parent": null}`, ""},
+		{"recursive", `{"name": "a", "parent": {"name": "b", "parent": {"name": "c"}}}`, ""},
+		{"recursive invalid", `{"name": "a", "parent": {"parent": {"name": "c"}}}`, `/parent: missing required property "name"`},
+		{"array items", `{"name": "a", "children": [{"name": "b"}, {"name": 2}]}`, "/children/1/name: expected string, got integer"},
+		{"null slice", `{"name": "a", "children": null, "labels": null, "data": null}`, ""},
+		{"map values", `{"name": "a", "labels": {"x": 1, "y/z~": "2"}}`, "/labels/y~1z~0: expected integer, got string"},
+		{"any value", `{"name": "a", "extra": {"nested": [null]}}`, ""},
+		{"date-time", `{"name": "a", "created": "2024-01-02T03:04:05Z"}`, ""},
+		{"definition", `{"name": "a", "renamed": {"Note": 3}}`, "/renamed/Note: expected string, got integer"},
+	}
+	for _, tt := range tests {
+		v, err := Decode([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", tt.name, err)
+		}
+		err = s.Validate(v)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: Validate = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		var verr *ValidationError
+		if !errors.As(err, &verr) || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: Validate = %v, want a ValidationError containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateReportsEveryError(t *testing.T) {
+	s := testReflector().Reflect(reflect.TypeOf(node{}), "Node")
+	v, err := Decode([]byte(`{"weight": "1", "level": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var verr *ValidationError
+	if !errors.As(s.Validate(v), &verr) {
+		t.Fatalf("Validate succeeded")
+	}
+	want := []string{
+		`/: missing required property "name"`,
+		"/level: expected number or string, got null",
+		"/weight: expected integer, got string",
+	}
+	if !reflect.DeepEqual(verr.Errors, want) || verr.Truncated {
+		t.Errorf("Validate errors = %q (truncated %v), want %q", verr.Errors, verr.Truncated, want)
+	}
+}
+
+func TestValidateTruncates(t *testing.T) {
+	s := &Schema{Type: Types{"array"}, Items: &Schema{Type: Types{"string"}}}
+	v, err := Decode([]byte(`[` + strings.Repeat(`1, `, maxErrors) + `1]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var verr *ValidationError
+	if !errors.As(s.Validate(v), &verr) {
+		t.Fatalf("Validate succeeded")
+	}
+	if len(verr.Errors) != maxErrors || !verr.Truncated || !strings.HasSuffix(verr.Error(), "; ...") {
+		t.Errorf("Validate returned %d errors, truncated %v, want %d and true", len(verr.Errors), verr.Truncated, maxErrors)
+	}
+}
+
+func TestValidateAnyOf(t *testing.T) {
+	s := &Schema{AnyOf: []*Schema{
+		{Type: Types{"string"}},
+		{Type: Types{"array"}, Items: &Schema{Type: Types{"string"}}},
+	}}
+	for _, ok := range []string{`"a"`, `["a", "b"]`} {
+		v, _ := Decode([]byte(ok))
+		if err := s.Validate(v); err != nil {
+			t.Errorf("Validate(%s) = %v, want nil", ok, err)
+		}
+	}
+	for _, bad := range []string{`1`, `[1]`, `null`} {
+		v, _ := Decode([]byte(bad))
+		if err := s.Validate(v); err == nil || !strings.Contains(err.Error(), "/: matches none of the allowed schemas") {
+			t.Errorf("Validate(%s) = %v, want no schema to match", bad, err)
+		}
+	}
+}
+
+func TestValidateUnresolvableRef(t *testing.T) {
+	s := &Schema{Ref: "#/$defs/missing"}
+	if err := s.Validate("x"); err == nil || !strings.Contains(err.Error(), "unresolvable $ref") {
+		t.Errorf("Validate = %v, want an unresolvable $ref error", err)
+	}
+}
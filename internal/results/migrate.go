@@ -0,0 +1,60 @@
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// migrations[v] upgrades a decoded results file of schema version v to
+// version v+1. Each works on the generic JSON value, so that it can move
+// and rename fields the current types no longer have.
+var migrations = map[int]func(v any) (any, error){
+	1: wrapResults,
+	2: addSchemaVersion,
+}
+
+// versionOf detects the schema version of a decoded results file. Files
+// written before versioning are told apart by their shape.
+func versionOf(v any) (int, error) {
+	switch doc := v.(type) {
+	case []any:
+		return 1, nil
+	case map[string]any:
+		raw, ok := doc["schema_version"]
+		if !ok {
+			return 2, nil
+		}
+		n, ok := raw.(json.Number)
+		if !ok {
+			return 0, fmt.Errorf("schema_version must be a number, got %v", raw)
+		}
+		version, err := strconv.Atoi(n.String())
+		if err != nil || version < 1 {
+			return 0, fmt.Errorf("invalid schema_version %s", n)
+		}
+		return version, nil
+	}
+	return 0, fmt.Errorf("results must be a JSON object or array")
+}
+
+// wrapResults turns the bare array of results written by the first
+// versions of cmd/benchmark into a document
+func wrapResults(v any) (any, error) {
+	results, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an array of results")
+	}
+	return map[string]any{"Results": results}, nil
+}
+
+// addSchemaVersion marks a document as version 3, which only added the
+// version itself
+func addSchemaVersion(v any) (any, error) {
+	doc, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a results document")
+	}
+	doc["schema_version"] = json.Number("3")
+	return doc, nil
+}
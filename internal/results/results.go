@@ -0,0 +1,136 @@
+// Package results reads and writes the results files of cmd/benchmark.
+//
+// Every file records the schema_version of its layout. Adding a field does
+// not change the version, since readers ignore fields they do not know and
+// files written before the field existed simply lack it. The version is
+// raised only when existing fields change name, type or meaning; Parse then
+// migrates files of every earlier version before validating them against
+// Schema, so old results stay readable.
+package results
+
+//go:generate go run schema_gen.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+
+	"github.com/natalie/go-flags-eval/internal/agentmetrics"
+	"github.com/natalie/go-flags-eval/internal/jsonschema"
+	"github.com/natalie/go-flags-eval/internal/runner"
+)
+
+// SchemaVersion is the version of the results file layout written by Write.
+//
+//	1: a bare array of results
+//	2: a document with Environment, Schedule, Results, Summaries and Builds
+//	3: the same document with schema_version
+const SchemaVersion = 3
+
+// Document is the results file of a sweep
+type Document struct {
+	SchemaVersion int `json:"schema_version"`
+	Environment   runner.Environment
+	Schedule      runner.Schedule
+	Results       []runner.BenchmarkResult
+	Summaries     []runner.BenchmarkSummary
+	Builds        []runner.AgentBuild
+}
+
+// Schema returns the JSON Schema of the current results file layout
+var Schema = sync.OnceValue(func() *jsonschema.Schema {
+	r := agentmetrics.NewSchemaReflector(map[reflect.Type][]string{
+		reflect.TypeOf(Document{}):               {"schema_version", "Results"},
+		reflect.TypeOf(runner.BenchmarkResult{}): {"Config", "Duration"},
+		reflect.TypeOf(runner.BenchmarkConfig{}): {"Name"},
+	})
+	return r.Reflect(reflect.TypeOf(Document{}), "Benchmark results")
+})
+
+// Write writes doc to filename with the current schema version
+func Write(filename string, doc Document) error {
+	doc.SchemaVersion = SchemaVersion
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal results: %w", err)
+	}
+
+	return os.WriteFile(filename, data, 0644)
+}
+
+// Read reads, migrates and validates a results file
+func Read(filename string) (Document, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return Document{}, err
+	}
+	doc, err := Parse(data)
+	if err != nil {
+		return Document{}, fmt.Errorf("%s: %w", filename, err)
+	}
+	return doc, nil
+}
+
+// Parse migrates a results file of any version to the current one,
+// validates it against Schema and decodes it
+func Parse(data []byte) (Document, error) {
+	v, err := jsonschema.Decode(data)
+	if err != nil {
+		return Document{}, err
+	}
+
+	version, err := versionOf(v)
+	if err != nil {
+		return Document{}, err
+	}
+	if version > SchemaVersion {
+		return Document{}, fmt.Errorf("results schema version %d is newer than the supported version %d", version, SchemaVersion)
+	}
+	for from := version; from < SchemaVersion; from++ {
+		if v, err = migrations[from](v); err != nil {
+			return Document{}, fmt.Errorf("migrating from schema version %d: %w", from, err)
+		}
+	}
+	if err := Schema().Validate(v); err != nil {
+		return Document{}, err
+	}
+
+	migrated, err := json.Marshal(v)
+	if err != nil {
+		return Document{}, err
+	}
+	var doc Document
+	if err := json.Unmarshal(migrated, &doc); err != nil {
+		return Document{}, err
+	}
+	if version < 3 && len(doc.Summaries) == 0 {
+		doc.Summaries = Summarize(doc.Results)
+	}
+	return doc, nil
+}
+
+// Summarize summarizes results per task/config pair, in the order the
+// pairs first appear
+func Summarize(results []runner.BenchmarkResult) []runner.BenchmarkSummary {
+	type pair struct{ task, config string }
+	index := map[pair]int{}
+	groups := [][]runner.BenchmarkResult{}
+	for _, r := range results {
+		key := pair{r.Task, r.Config.Name}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], r)
+	}
+
+	summaries := make([]runner.BenchmarkSummary, 0, len(groups))
+	for _, group := range groups {
+		summaries = append(summaries, runner.Summarize(group[0].Task, group[0].Config, group))
+	}
+	return summaries
+}
@@ -0,0 +1,118 @@
+package results
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/runner"
+)
+
+const v1 = `[
+  {"Task": "file-search", "Config": {"Name": "default", "GCPercent": 100}, "Duration": 41000000, "Repetition": 1},
+  {"Task": "file-search", "Config": {"Name": "default", "GCPercent": 100}, "Duration": 43000000, "Repetition": 2},
+  {"Task": "file-search", "Config": {"Name": "gc-off", "GCPercent": -1}, "Duration": 35000000, "Repetition": 1}
+]`
+
+const v2 = `{
+  "Environment": {"GoVersion": "go1.24.4"},
+  "Results": [
+    {"Task": "file-search", "Config": {"Name": "default", "GCPercent": 100}, "Duration": 41000000, "Repetition": 1},
+    {"Task": "file-search", "Config": {"Name": "gc-off", "GCPercent": -1}, "Duration": 35000000, "Repetition": 1}
+  ]
+}`
+
+const v3 = `{
+  "schema_version": 3,
+  "Results": [
+    {"Task": "file-search", "Config": {"Name": "default", "GCPercent": 100}, "Duration": 41000000, "Repetition": 1}
+  ],
+  "Summaries": [
+    {"Task": "file-search", "Config": {"Name": "default", "GCPercent": 100}}
+  ]
+}`
+
+func TestParseMigrates(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		results   int
+		summaries int
+	}{
+		{"bare array", v1, 3, 2},
+		{"without schema_version", v2, 2, 2},
+		{"current", v3, 1, 1},
+	}
+	for _, tt := range tests {
+		doc, err := Parse([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: Parse: %v", tt.name, err)
+			continue
+		}
+		if doc.SchemaVersion != SchemaVersion {
+			t.Errorf("%s: schema version %d, want %d", tt.name, doc.SchemaVersion, SchemaVersion)
+		}
+		if len(doc.Results) != tt.results || len(doc.Summaries) != tt.summaries {
+			t.Errorf("%s: %d results and %d summaries, want %d and %d", tt.name, len(doc.Results), len(doc.Summaries), tt.results, tt.summaries)
+		}
+		if r := doc.Results[0]; r.Task != "file-search" || r.Config.Name != "default" || r.Duration != 41*time.Millisecond {
+			t.Errorf("%s: first result decoded as %+v", tt.name, r)
+		}
+	}
+}
+
+func TestParseSummarizesOldFiles(t *testing.T) {
+	doc, err := Parse([]byte(v1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := doc.Summaries[0]
+	if s.Task != "file-search" || s.Config.Name != "default" || s.Runs != 2 {
+		t.Errorf("first summary = %s/%s over %d runs, want file-search/default over 2", s.Task, s.Config.Name, s.Runs)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"newer version", `{"schema_version": 4, "Results": []}`, "newer than the supported version"},
+		{"version not a number", `{"schema_version": "3", "Results": []}`, "must be a number"},
+		{"version zero", `{"schema_version": 0, "Results": []}`, "invalid schema_version"},
+		{"scalar", `42`, "JSON object or array"},
+		{"not JSON", `{"Results": [`, ""},
+		{"missing results", `{"schema_version": 3}`, "Results"},
+		{"result without config", `[{"Task": "a", "Duration": 1}]`, "Config"},
+		{"duration of the wrong type", `{"schema_version": 3, "Results": [{"Config": {"Name": "default"}, "Duration": "1s"}]}`, "Duration"},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: Parse error = %v, want one containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "results.json")
+	results := []runner.BenchmarkResult{
+		{Task: "refactor", Config: runner.BenchmarkConfig{Name: "maxprocs-2", MaxProcs: 2, GCPercent: 100}, Duration: time.Second, Repetition: 1},
+	}
+	if err := Write(filename, Document{Results: results, Summaries: Summarize(results)}); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := Read(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.SchemaVersion != SchemaVersion || len(doc.Results) != 1 || doc.Results[0].Config != results[0].Config {
+		t.Errorf("Read = %+v, want the written document", doc)
+	}
+	if _, err := Read(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Read of a missing file succeeded")
+	}
+}
@@ -0,0 +1,34 @@
+//go:build ignore
+
+// schema_gen writes the JSON Schema documents of results and agent metrics
+// files to docs/schema. Run it with go generate after changing the types.
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/natalie/go-flags-eval/internal/agentmetrics"
+	"github.com/natalie/go-flags-eval/internal/jsonschema"
+	"github.com/natalie/go-flags-eval/internal/results"
+)
+
+func main() {
+	dir := filepath.Join("..", "..", "docs", "schema")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatal(err)
+	}
+	write(filepath.Join(dir, "results.schema.json"), results.Schema())
+	write(filepath.Join(dir, "metrics.schema.json"), agentmetrics.Schema())
+}
+
+func write(filename string, schema *jsonschema.Schema) {
+	data, err := schema.MarshalIndent()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
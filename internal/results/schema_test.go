@@ -0,0 +1,31 @@
+package results
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/natalie/go-flags-eval/internal/agentmetrics"
+	"github.com/natalie/go-flags-eval/internal/jsonschema"
+)
+
+// The checked-in schemas must match the types; schema_gen.go writes them
+func TestSchemaUpToDate(t *testing.T) {
+	for name, schema := range map[string]*jsonschema.Schema{
+		"results.schema.json": Schema(),
+		"metrics.schema.json": agentmetrics.Schema(),
+	} {
+		want, err := schema.MarshalIndent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join("..", "..", "docs", "schema", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("docs/schema/%s is out of date, run go generate ./internal/results", name)
+		}
+	}
+}
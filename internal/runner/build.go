@@ -0,0 +1,203 @@
+package runner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AgentBuild records how an agent binary was produced
+type AgentBuild struct {
+	Task       string
+	Package    string
+	Binary     string
+	SourceHash string
+	BuildTime  time.Duration
+	Cached     bool
+	Test       bool   `json:",omitempty"` // Built with go test -c
+	Dir        string `json:",omitempty"` // Package directory, where test binaries run
+}
+
+// BuildTask builds task's binary: a test binary for Go benchmark tasks,
+// the agent itself otherwise
+func BuildTask(cacheDir string, task AgentTask) (AgentBuild, error) {
+	if task.Bench != "" {
+		return build(cacheDir, task.Name, task.Package, true)
+	}
+	return BuildAgent(cacheDir, task.Name, task.Package)
+}
+
+// BuildAgent compiles pkg into cacheDir, reusing an existing binary when
+// the module-local sources it depends on have not changed
+func BuildAgent(cacheDir, task, pkg string) (AgentBuild, error) {
+	return build(cacheDir, task, pkg, false)
+}
+
+func build(cacheDir, task, pkg string, test bool) (AgentBuild, error) {
+	build := AgentBuild{
+		Task:    task,
+		Package: pkg,
+		Test:    test,
+	}
+
+	var err error
+	build.Binary, build.SourceHash, err = binaryPath(cacheDir, pkg, test)
+	if err != nil {
+		return build, err
+	}
+	if test {
+		if build.Dir, err = PackageDir(pkg); err != nil {
+			return build, err
+		}
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return build, fmt.Errorf("failed to create build cache dir: %w", err)
+	}
+
+	if _, err := os.Stat(build.Binary); err == nil {
+		build.Cached = true
+		return build, nil
+	}
+
+	// Build into a temporary name first so an interrupted build never
+	// leaves a truncated binary behind under the cached name
+	tmp := build.Binary + ".tmp"
+	cmd := exec.Command("go", "build", "-o", tmp, pkg)
+	if test {
+		cmd = exec.Command("go", "test", "-c", "-o", tmp, pkg)
+	}
+	cmd.Env = buildEnv()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err = cmd.Run()
+	build.BuildTime = time.Since(start)
+	if err != nil {
+		os.Remove(tmp)
+		return build, fmt.Errorf("%s: %w\n%s", strings.Join(cmd.Args, " "), err, stderr.String())
+	}
+	// go test -c succeeds without writing anything for packages that
+	// have no tests
+	if _, err := os.Stat(tmp); err != nil {
+		return build, fmt.Errorf("%s has no test files", pkg)
+	}
+
+	if err := os.Rename(tmp, build.Binary); err != nil {
+		return build, fmt.Errorf("failed to install binary: %w", err)
+	}
+
+	return build, nil
+}
+
+// BinaryPath returns where BuildTask puts task's binary for the current
+// sources, without building it
+func BinaryPath(cacheDir string, task AgentTask) (string, error) {
+	path, _, err := binaryPath(cacheDir, task.Package, task.Bench != "")
+	return path, err
+}
+
+// PackageDir returns the source directory of pkg. Benchmarks run there,
+// as under go test, so that they find their testdata.
+func PackageDir(pkg string) (string, error) {
+	out, err := exec.Command("go", "list", "-f", "{{.Dir}}", pkg).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the directory of %s: %w", pkg, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func binaryPath(cacheDir, pkg string, test bool) (path, hash string, err error) {
+	hash, err = sourceHash(pkg, test)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to hash sources of %s: %w", pkg, err)
+	}
+	path = filepath.Join(cacheDir, fmt.Sprintf("%s-%s", filepath.Base(pkg), hash[:16]))
+	if test {
+		path += ".test"
+	}
+	return path, hash, nil
+}
+
+// buildEnv returns the runner's environment without the runtime flags
+// under test, so the compiler always runs with the same settings
+func buildEnv() []string {
+	env := []string{}
+	for _, kv := range os.Environ() {
+		switch {
+		case strings.HasPrefix(kv, "GOMAXPROCS="),
+			strings.HasPrefix(kv, "GOMEMLIMIT="),
+			strings.HasPrefix(kv, "GOGC="):
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// sourceHash hashes the Go toolchain version, go.mod/go.sum and every
+// source file of the module-local packages pkg depends on, including
+// test files and test dependencies when test is set
+func sourceHash(pkg string, test bool) (string, error) {
+	list := "{{range .GoFiles}} {{.}}{{end}}"
+	args := []string{"list", "-deps"}
+	if test {
+		list += "{{range .TestGoFiles}} {{.}}{{end}}{{range .XTestGoFiles}} {{.}}{{end}}"
+		args = append(args, "-test")
+	}
+	args = append(args, "-f", "{{if and .Module .Module.Main}}{{.Dir}}"+list+"{{end}}", pkg)
+	out, err := exec.Command("go", args...).Output()
+	if err != nil {
+		return "", err
+	}
+
+	version, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write(version)
+
+	files := []string{"go.mod", "go.sum"}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		for _, name := range fields[1:] {
+			files = append(files, filepath.Join(fields[0], name))
+		}
+	}
+
+	for _, file := range files {
+		if err := hashFile(h, file); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(w, "%s\x00", filename)
+	_, err = io.Copy(w, f)
+	return err
+}
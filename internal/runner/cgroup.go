@@ -0,0 +1,50 @@
+package runner
+
+import "fmt"
+
+// CgroupStats are the counters of the transient cgroup a run was placed
+// in, read just before the cgroup is removed
+type CgroupStats struct {
+	Path       string
+	MemoryMax  string // As written to memory.max
+	MemoryHigh string // As written to memory.high
+	CPUMax     string // As written to cpu.max
+	MemoryPeak uint64 `json:",omitempty"` // memory.peak, Linux 5.19+
+
+	// memory.events
+	MemoryEventsLow     uint64
+	MemoryEventsHigh    uint64 // Times usage went over memory.high and was throttled
+	MemoryEventsMax     uint64 // Times usage hit memory.max
+	MemoryEventsOOM     uint64
+	MemoryEventsOOMKill uint64
+
+	// cpu.stat
+	CPUUsageUsec     uint64
+	CPUUserUsec      uint64
+	CPUSystemUsec    uint64
+	CPUNrPeriods     uint64
+	CPUNrThrottled   uint64 // Periods in which the cgroup hit its cpu.max quota
+	CPUThrottledUsec uint64
+}
+
+// HasCgroupLimits reports whether cfg asks for container-style limits
+func HasCgroupLimits(cfg BenchmarkConfig) bool {
+	return cfg.MemoryMax > 0 || cfg.MemoryHigh > 0 || cfg.CPUs > 0
+}
+
+// cgroupLimitValues renders cfg's limits in cgroup v2 file syntax
+func cgroupLimitValues(cfg BenchmarkConfig) (memoryMax, memoryHigh, cpuMax string) {
+	const cpuPeriod = 100000
+
+	memoryMax, memoryHigh, cpuMax = "max", "max", fmt.Sprintf("max %d", cpuPeriod)
+	if cfg.MemoryMax > 0 {
+		memoryMax = fmt.Sprintf("%d", cfg.MemoryMax<<20)
+	}
+	if cfg.MemoryHigh > 0 {
+		memoryHigh = fmt.Sprintf("%d", cfg.MemoryHigh<<20)
+	}
+	if cfg.CPUs > 0 {
+		cpuMax = fmt.Sprintf("%d %d", int64(cfg.CPUs*cpuPeriod), cpuPeriod)
+	}
+	return memoryMax, memoryHigh, cpuMax
+}
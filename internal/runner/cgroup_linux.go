@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+var cgroupSeq atomic.Int64
+
+// cgroupLeaf is a transient cgroup v2 leaf holding exactly one run
+type cgroupLeaf struct {
+	path  string
+	dir   *os.File
+	stats CgroupStats
+}
+
+// createCgroupLeaf creates a new leaf below root with cfg's limits. root
+// must be a cgroup v2 directory delegated to the current user, and must
+// not contain processes itself.
+func createCgroupLeaf(root string, cfg BenchmarkConfig) (*cgroupLeaf, error) {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("%s is not a cgroup v2 directory: %w", root, err)
+	}
+
+	// Make the memory and cpu controllers available to our leaves
+	if err := writeCgroupFile(root, "cgroup.subtree_control", "+memory +cpu"); err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("run-%d-%d", os.Getpid(), cgroupSeq.Add(1))
+	leaf := &cgroupLeaf{path: filepath.Join(root, name)}
+	if err := os.Mkdir(leaf.path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+
+	memoryMax, memoryHigh, cpuMax := cgroupLimitValues(cfg)
+	leaf.stats = CgroupStats{
+		Path:       leaf.path,
+		MemoryMax:  memoryMax,
+		MemoryHigh: memoryHigh,
+		CPUMax:     cpuMax,
+	}
+
+	limits := []struct{ file, value string }{
+		{"memory.max", memoryMax},
+		{"memory.high", memoryHigh},
+		{"cpu.max", cpuMax},
+	}
+	for _, l := range limits {
+		if err := writeCgroupFile(leaf.path, l.file, l.value); err != nil {
+			leaf.remove()
+			return nil, err
+		}
+	}
+
+	// Like a container without swap, make memory.max a hard limit.
+	// memory.swap.max is absent when swap accounting is disabled.
+	if cfg.MemoryMax > 0 {
+		if err := writeCgroupFile(leaf.path, "memory.swap.max", "0"); err != nil && !errors.Is(err, os.ErrNotExist) {
+			leaf.remove()
+			return nil, err
+		}
+	}
+
+	dir, err := os.Open(leaf.path)
+	if err != nil {
+		leaf.remove()
+		return nil, fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	leaf.dir = dir
+
+	return leaf, nil
+}
+
+// apply makes cmd start directly inside the leaf, so no part of the
+// child ever runs outside its limits
+func (l *cgroupLeaf) apply(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(l.dir.Fd())
+}
+
+// collect reads the leaf's memory.events, memory.peak and cpu.stat
+func (l *cgroupLeaf) collect() CgroupStats {
+	s := l.stats
+
+	events := readKeyedFile(filepath.Join(l.path, "memory.events"))
+	s.MemoryEventsLow = events["low"]
+	s.MemoryEventsHigh = events["high"]
+	s.MemoryEventsMax = events["max"]
+	s.MemoryEventsOOM = events["oom"]
+	s.MemoryEventsOOMKill = events["oom_kill"]
+
+	cpu := readKeyedFile(filepath.Join(l.path, "cpu.stat"))
+	s.CPUUsageUsec = cpu["usage_usec"]
+	s.CPUUserUsec = cpu["user_usec"]
+	s.CPUSystemUsec = cpu["system_usec"]
+	s.CPUNrPeriods = cpu["nr_periods"]
+	s.CPUNrThrottled = cpu["nr_throttled"]
+	s.CPUThrottledUsec = cpu["throttled_usec"]
+
+	if peak, err := strconv.ParseUint(readTrimmed(filepath.Join(l.path, "memory.peak")), 10, 64); err == nil {
+		s.MemoryPeak = peak
+	}
+
+	return s
+}
+
+// remove deletes the leaf. The kernel may report the cgroup busy for a
+// moment after its last process exits, so removal is retried briefly.
+func (l *cgroupLeaf) remove() error {
+	if l.dir != nil {
+		l.dir.Close()
+	}
+	var err error
+	for i := 0; i < 20; i++ {
+		if err = os.Remove(l.path); err == nil || errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Errorf("failed to remove cgroup %s: %w", l.path, err)
+}
+
+func writeCgroupFile(dir, file, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to write %q to %s: %w", value, filepath.Join(dir, file), err)
+	}
+	return nil
+}
+
+// readKeyedFile parses a flat-keyed cgroup file of "key value" lines
+func readKeyedFile(filename string) map[string]uint64 {
+	values := map[string]uint64{}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return values
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values
+}
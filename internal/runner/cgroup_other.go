@@ -0,0 +1,20 @@
+//go:build !linux
+
+package runner
+
+import (
+	"errors"
+	"os/exec"
+)
+
+type cgroupLeaf struct{}
+
+func createCgroupLeaf(root string, cfg BenchmarkConfig) (*cgroupLeaf, error) {
+	return nil, errors.New("cgroup mode requires Linux with cgroup v2")
+}
+
+func (l *cgroupLeaf) apply(cmd *exec.Cmd) {}
+
+func (l *cgroupLeaf) collect() CgroupStats { return CgroupStats{} }
+
+func (l *cgroupLeaf) remove() error { return nil }
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/stats"
+)
+
+// CoTenancyStats describes a group of agent processes that shared the
+// machine during one run
+type CoTenancyStats struct {
+	Processes  int
+	Failed     int
+	StartSkew  time.Duration // Between the first and the last process start
+	Throughput float64       // Successful processes per second of makespan
+
+	// Distribution of the successful processes' own durations
+	DurationMean time.Duration
+	DurationP50  time.Duration
+	DurationP90  time.Duration
+	DurationP99  time.Duration
+	DurationMax  time.Duration
+}
+
+// runGroup starts every tenant of task at the same moment under cfg and
+// returns their joint result. All processes are prepared first (inputs
+// staged, cgroup leaves created) and then released together by a start
+// barrier. Duration is the makespan, from the barrier until the last
+// process exited; allocations, GC and CPU time are summed over the
+// processes, as is peak RSS, which bounds their combined footprint. Each
+// process's own result is kept in Tenants.
+func (r *Runner) runGroup(ctx context.Context, task AgentTask, cfg BenchmarkConfig) BenchmarkResult {
+	result := BenchmarkResult{
+		Task:            task.Name,
+		TaskDescription: task.Description,
+		Config:          cfg,
+	}
+
+	procs := make([]*process, len(task.Tenants))
+	for i, tenant := range task.Tenants {
+		procs[i] = r.prepare(ctx, tenant, cfg)
+		defer procs[i].close()
+		if procs[i].cmd == nil {
+			result.Tenants = append(result.Tenants, procs[i].result)
+			result.Failure = &Failure{
+				Class:   FailureStart,
+				Message: fmt.Sprintf("tenant %d (%s): %s", i+1, tenant.Name, procs[i].result.Error),
+			}
+			result.Error = result.Failure.Message
+			return result
+		}
+	}
+
+	var ready, exited sync.WaitGroup
+	barrier := make(chan struct{})
+	oomBefore := oomKillCount()
+	for _, p := range procs {
+		p.oomBefore = oomBefore
+		ready.Add(1)
+		exited.Add(1)
+		go func() {
+			defer exited.Done()
+			ready.Done()
+			<-barrier
+			p.run(r.ProcInterval, r.OnSample)
+		}()
+	}
+	ready.Wait()
+	start := time.Now()
+	close(barrier)
+	exited.Wait()
+	result.Duration = time.Since(start)
+
+	co := &CoTenancyStats{Processes: len(procs)}
+	first, last := procs[0].started, procs[0].started
+	durations := []float64{}
+	for i, p := range procs {
+		tenant := p.finish()
+		tenant.Repetition = i + 1
+		result.Tenants = append(result.Tenants, tenant)
+		if p.started.Before(first) {
+			first = p.started
+		}
+		if p.started.After(last) {
+			last = p.started
+		}
+
+		if tenant.Failure != nil {
+			co.Failed++
+			if result.Failure == nil {
+				result.Failure = &Failure{Class: tenant.Failure.Class, Signal: tenant.Failure.Signal, ExitCode: tenant.Failure.ExitCode}
+				result.Failure.Message = fmt.Sprintf("tenant %d (%s): %s", i+1, tenant.Task, tenant.Error)
+			}
+			continue
+		}
+		durations = append(durations, float64(tenant.Duration))
+		result.MemoryAllocated += tenant.MemoryAllocated
+		result.NumGC += tenant.NumGC
+		result.PauseTimeNs += tenant.PauseTimeNs
+		addResources(&result.Resources, tenant.Resources)
+	}
+	co.StartSkew = last.Sub(first)
+
+	if result.Failure != nil {
+		if co.Failed > 1 {
+			result.Failure.Message = fmt.Sprintf("%d of %d tenants failed, first %s", co.Failed, co.Processes, result.Failure.Message)
+		}
+		result.Error = result.Failure.Message
+		result.ExitCode = result.Failure.ExitCode
+	}
+
+	if len(durations) > 0 {
+		co.Throughput = float64(len(durations)) / result.Duration.Seconds()
+		co.DurationMean = time.Duration(stats.Summarize(durations).Mean)
+		co.DurationP50 = time.Duration(stats.Percentile(durations, 50))
+		co.DurationP90 = time.Duration(stats.Percentile(durations, 90))
+		co.DurationP99 = time.Duration(stats.Percentile(durations, 99))
+		co.DurationMax = time.Duration(stats.Percentile(durations, 100))
+	}
+	result.CoTenancy = co
+	return result
+}
+
+// addResources adds a tenant's usage to the group's
+func addResources(total *ResourceUsage, u ResourceUsage) {
+	total.PeakRSS += u.PeakRSS
+	total.UserCPU += u.UserCPU
+	total.SystemCPU += u.SystemCPU
+	total.VoluntaryCtxSwitches += u.VoluntaryCtxSwitches
+	total.InvoluntaryCtxSwitches += u.InvoluntaryCtxSwitches
+	total.MinorPageFaults += u.MinorPageFaults
+	total.MajorPageFaults += u.MajorPageFaults
+	total.BlockInputOps += u.BlockInputOps
+	total.BlockOutputOps += u.BlockOutputOps
+	total.ProcSamples += u.ProcSamples
+	total.ProcPeakRSS += u.ProcPeakRSS
+	total.ProcPeakThreads += u.ProcPeakThreads
+	total.ReadChars += u.ReadChars
+	total.WriteChars += u.WriteChars
+	total.ReadBytes += u.ReadBytes
+	total.WriteBytes += u.WriteBytes
+}
@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Environment describes the machine and source tree a benchmark run was
+// taken on, so results from different hosts and days can be compared
+type Environment struct {
+	Timestamp    time.Time
+	Hostname     string
+	GoVersion    string // Toolchain used to build the agents
+	RunnerGo     string // Toolchain the runner itself was built with
+	GOOS         string
+	GOARCH       string
+	CPUModel     string
+	NumCPU       int
+	Kernel       string
+	CgroupMemory string // memory.max (v2) or memory.limit_in_bytes (v1), empty if unknown
+	CgroupCPU    string // cpu.max (v2) or cfs quota/period (v1), empty if unknown
+	GitCommit    string
+	GitDirty     bool
+}
+
+// CollectEnvironment gathers best-effort metadata about the host; fields
+// that cannot be determined are left empty
+func CollectEnvironment() Environment {
+	env := Environment{
+		Timestamp: time.Now().UTC(),
+		RunnerGo:  runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+	}
+
+	env.Hostname, _ = os.Hostname()
+	env.GoVersion = commandOutput("go", "env", "GOVERSION")
+	env.CPUModel = cpuModel()
+	env.Kernel = kernelVersion()
+	env.CgroupMemory, env.CgroupCPU = cgroupLimits()
+	env.GitCommit = commandOutput("git", "rev-parse", "HEAD")
+	if env.GitCommit != "" {
+		env.GitDirty = commandOutput("git", "status", "--porcelain", "--untracked-files=no") != ""
+	}
+
+	return env
+}
+
+func commandOutput(name string, args ...string) string {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func readTrimmed(filename string) string {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func cpuModel() string {
+	if runtime.GOOS == "darwin" {
+		return commandOutput("sysctl", "-n", "machdep.cpu.brand_string")
+	}
+
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "model name", "Model", "cpu model":
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func kernelVersion() string {
+	if v := readTrimmed("/proc/sys/kernel/osrelease"); v != "" {
+		return v
+	}
+	return commandOutput("uname", "-r")
+}
+
+// cgroupLimits returns the memory and CPU limits of the runner's cgroup
+func cgroupLimits() (memory, cpu string) {
+	// cgroup v2 exposes a unified hierarchy
+	if memory = readTrimmed("/sys/fs/cgroup/memory.max"); memory != "" {
+		return memory, readTrimmed("/sys/fs/cgroup/cpu.max")
+	}
+
+	// cgroup v1
+	memory = readTrimmed("/sys/fs/cgroup/memory/memory.limit_in_bytes")
+	quota := readTrimmed("/sys/fs/cgroup/cpu/cpu.cfs_quota_us")
+	period := readTrimmed("/sys/fs/cgroup/cpu/cpu.cfs_period_us")
+	if quota != "" && period != "" {
+		cpu = quota + " " + period
+	}
+	return memory, cpu
+}
@@ -0,0 +1,140 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FailureClass categorizes why a run did not produce usable results
+type FailureClass string
+
+const (
+	FailureStart          FailureClass = "start"           // The process could not be started
+	FailureTimeout        FailureClass = "timeout"         // Killed after exceeding its timeout
+	FailureOOMKill        FailureClass = "oom-kill"        // SIGKILLed by the kernel OOM killer
+	FailureSignal         FailureClass = "signal"          // Terminated by any other signal
+	FailureExit           FailureClass = "exit"            // Exited with a non-zero status
+	FailureMissingMetrics FailureClass = "missing-metrics" // Exited cleanly but wrote no metrics
+	FailureInputModified  FailureClass = "input-modified"  // The task's source tree, or a read-only task's copy, changed
+)
+
+// Failure describes a failed run
+type Failure struct {
+	Class    FailureClass
+	Signal   string `json:",omitempty"` // e.g. SIGKILL, for signal and oom-kill failures
+	ExitCode int    `json:",omitempty"`
+	OOMKills uint64 `json:",omitempty"` // OOM kills observed while the run was active
+	Message  string
+}
+
+// classifyFailure turns the outcome of cmd.Wait into a Failure, or nil if
+// the process exited successfully. oomKills is the number of OOM kills
+// observed while the process ran.
+func classifyFailure(ctx context.Context, cmd *exec.Cmd, waitErr error, oomKills uint64) *Failure {
+	if waitErr == nil {
+		return nil
+	}
+
+	if cmd.ProcessState == nil {
+		return &Failure{Class: FailureStart, Message: waitErr.Error()}
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &Failure{
+			Class:    FailureTimeout,
+			Signal:   exitSignal(cmd.ProcessState),
+			ExitCode: cmd.ProcessState.ExitCode(),
+			Message:  "timed out: " + waitErr.Error(),
+		}
+	}
+
+	if sig := exitSignal(cmd.ProcessState); sig != "" {
+		failure := &Failure{
+			Class:    FailureSignal,
+			Signal:   sig,
+			ExitCode: cmd.ProcessState.ExitCode(),
+			OOMKills: oomKills,
+			Message:  waitErr.Error(),
+		}
+		if sig == "SIGKILL" && oomKills > 0 {
+			failure.Class = FailureOOMKill
+			failure.Message = fmt.Sprintf("killed by the OOM killer (%d OOM kill events)", oomKills)
+		}
+		return failure
+	}
+
+	return &Failure{
+		Class:    FailureExit,
+		ExitCode: cmd.ProcessState.ExitCode(),
+		Message:  waitErr.Error(),
+	}
+}
+
+// oomKillCount returns the number of OOM kills recorded so far, from the
+// runner's own cgroup (v2 memory.events or v1 memory.oom_control) plus
+// the system-wide counter in /proc/vmstat. Only the difference between two
+// calls is meaningful. It returns 0 where neither source exists.
+func oomKillCount() uint64 {
+	var total uint64
+	for _, source := range oomKillSources() {
+		total += readCounter(source.file, source.key)
+	}
+	return total
+}
+
+type counterSource struct {
+	file string
+	key  string
+}
+
+func oomKillSources() []counterSource {
+	sources := []counterSource{{"/proc/vmstat", "oom_kill"}}
+
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return sources
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Lines look like "0::/path" (v2) or "4:memory:/path" (v1)
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			sources = append(sources, counterSource{filepath.Join("/sys/fs/cgroup", parts[2], "memory.events"), "oom_kill"})
+		case parts[1] == "memory":
+			sources = append(sources, counterSource{filepath.Join("/sys/fs/cgroup/memory", parts[2], "memory.oom_control"), "oom_kill"})
+		}
+	}
+	return sources
+}
+
+// readCounter reads a "key value" line from a flat-keyed kernel file
+func readCounter(filename, key string) uint64 {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			v, _ := strconv.ParseUint(fields[1], 10, 64)
+			return v
+		}
+	}
+	return 0
+}
@@ -0,0 +1,403 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Plan describes a benchmark sweep: the runtime configurations to test,
+// the agent tasks to run under each of them and how often
+type Plan struct {
+	Count       int          `json:"count,omitempty"`       // Repetitions per task/config pair
+	Timeout     Duration     `json:"timeout,omitempty"`     // Default per-run timeout
+	Order       string       `json:"order,omitempty"`       // sequential, round-robin or shuffle
+	Seed        int64        `json:"seed,omitempty"`        // Shuffle seed
+	Warmup      *int         `json:"warmup,omitempty"`      // Discarded runs per task/config pair
+	Configs     []PlanConfig `json:"configs,omitempty"`     // Explicit configs
+	Sweep       string       `json:"sweep,omitempty"`       // Sweep expression expanded into more configs
+	Constraints []string     `json:"constraints,omitempty"` // Constraints pruning the sweep
+	Tasks       []PlanTask   `json:"tasks"`
+}
+
+// PlanConfig is the plan file form of a BenchmarkConfig
+type PlanConfig struct {
+	Name       string   `json:"name"`
+	MaxProcs   int      `json:"gomaxprocs,omitempty"`    // 0 = runtime default
+	MemLimitMB int64    `json:"gomemlimit_mb,omitempty"` // 0 = no limit
+	GCPercent  *int     `json:"gogc,omitempty"`          // nil = 100
+	Timeout    Duration `json:"timeout,omitempty"`       // Per-run timeout, the shorter of this and the task's applies
+
+	// Container-style limits, enforced only with -cgroup-root
+	MemoryMaxMB  int64   `json:"memory_max_mb,omitempty"`
+	MemoryHighMB int64   `json:"memory_high_mb,omitempty"`
+	CPUs         float64 `json:"cpus,omitempty"`
+}
+
+// PlanTask is the plan file form of an AgentTask
+type PlanTask struct {
+	Name        string   `json:"name"`
+	Package     string   `json:"package,omitempty"`
+	Command     string   `json:"command,omitempty"`
+	Args        []string `json:"args,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Timeout     Duration `json:"timeout,omitempty"`
+	Count       int      `json:"count,omitempty"`
+
+	// Go benchmarks of package, run from a go test -c binary
+	Bench      string `json:"bench,omitempty"`       // -test.bench regexp
+	BenchTime  string `json:"benchtime,omitempty"`   // -test.benchtime
+	BenchCount int    `json:"bench_count,omitempty"` // -test.count per run
+
+	// Input tree copied afresh for every run, substituted for {input} in args
+	Input    string `json:"input,omitempty"`
+	ReadOnly bool   `json:"read_only,omitempty"`
+
+	// Co-tenancy group: other tasks of the plan started together
+	Tenants []PlanTenant `json:"tenants,omitempty"`
+}
+
+// PlanTenant adds count processes of another task to a co-tenancy group
+type PlanTenant struct {
+	Task  string `json:"task"`
+	Count int    `json:"count,omitempty"` // 0 = 1
+}
+
+// Duration is a time.Duration that reads and writes as a Go duration
+// string such as "90s" or "5m"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"90s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// LoadPlan reads and validates a plan file
+func LoadPlan(filename string) (*Plan, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var plan Plan
+	if err := dec.Decode(&plan); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	if err := plan.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: invalid plan:\n%w", filename, err)
+	}
+
+	return &plan, nil
+}
+
+// Validate reports every problem in the plan, each prefixed with the
+// location of the offending entry
+func (p *Plan) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if p.Count < 0 {
+		fail("count: must not be negative, got %d", p.Count)
+	}
+	if p.Timeout < 0 {
+		fail("timeout: must not be negative, got %v", time.Duration(p.Timeout))
+	}
+	if p.Order != "" {
+		if _, err := ParseRunOrder(p.Order); err != nil {
+			fail("order: %v", err)
+		}
+	}
+	if p.Warmup != nil && *p.Warmup < 0 {
+		fail("warmup: must not be negative, got %d", *p.Warmup)
+	}
+
+	if len(p.Configs) == 0 && p.Sweep == "" {
+		fail("configs: at least one config or a sweep is required")
+	}
+	if p.Sweep == "" && len(p.Constraints) > 0 {
+		fail("constraints: only allowed together with a sweep")
+	}
+	seen := map[string]int{}
+	for i, c := range p.Configs {
+		where := fmt.Sprintf("configs[%d] (%q)", i, c.Name)
+		if c.Name == "" {
+			fail("configs[%d]: name is required", i)
+		} else if prev, ok := seen[c.Name]; ok {
+			fail("%s: duplicate name, first defined at configs[%d]", where, prev)
+		} else {
+			seen[c.Name] = i
+		}
+		if c.MaxProcs < 0 {
+			fail("%s: gomaxprocs must not be negative, got %d", where, c.MaxProcs)
+		}
+		if c.MemLimitMB < 0 {
+			fail("%s: gomemlimit_mb must not be negative, got %d", where, c.MemLimitMB)
+		}
+		if c.GCPercent != nil && *c.GCPercent < -1 {
+			fail("%s: gogc must be -1 (off) or a non-negative percentage, got %d", where, *c.GCPercent)
+		}
+		if c.Timeout < 0 {
+			fail("%s: timeout must not be negative, got %v", where, time.Duration(c.Timeout))
+		}
+		if c.MemoryMaxMB < 0 || c.MemoryHighMB < 0 || c.CPUs < 0 {
+			fail("%s: memory_max_mb, memory_high_mb and cpus must not be negative", where)
+		}
+		if c.MemoryMaxMB > 0 && c.MemoryHighMB > c.MemoryMaxMB {
+			fail("%s: memory_high_mb (%d) is above memory_max_mb (%d)", where, c.MemoryHighMB, c.MemoryMaxMB)
+		}
+	}
+
+	if p.Sweep != "" {
+		sweep, err := ParseSweep(p.Sweep, p.Constraints)
+		if err != nil {
+			fail("sweep: %v", err)
+		} else if expanded := sweep.Expand(); len(expanded) == 0 {
+			fail("sweep: all %d points are excluded by the constraints", sweep.Size())
+		} else {
+			for _, c := range expanded {
+				if prev, ok := seen[c.Name]; ok {
+					fail("sweep: generated config %q clashes with configs[%d]", c.Name, prev)
+				}
+			}
+		}
+	}
+
+	if len(p.Tasks) == 0 {
+		fail("tasks: at least one task is required")
+	}
+	seen = map[string]int{}
+	for i, t := range p.Tasks {
+		where := fmt.Sprintf("tasks[%d] (%q)", i, t.Name)
+		if t.Name == "" {
+			fail("tasks[%d]: name is required", i)
+		} else if prev, ok := seen[t.Name]; ok {
+			fail("%s: duplicate name, first defined at tasks[%d]", where, prev)
+		} else {
+			seen[t.Name] = i
+		}
+		switch {
+		case len(t.Tenants) > 0:
+			validateTenants(p, i, where, fail)
+		case t.Package == "" && t.Command == "":
+			fail("%s: one of package, command or tenants is required", where)
+		case t.Package != "" && t.Command != "":
+			fail("%s: package and command are mutually exclusive", where)
+		}
+		if t.Timeout < 0 {
+			fail("%s: timeout must not be negative, got %v", where, time.Duration(t.Timeout))
+		}
+		if t.Count < 0 {
+			fail("%s: count must not be negative, got %d", where, t.Count)
+		}
+		if t.Bench != "" {
+			if t.Package == "" {
+				fail("%s: bench requires a package", where)
+			}
+			if _, err := regexp.Compile(t.Bench); err != nil {
+				fail("%s: bench: %v", where, err)
+			}
+		} else if t.BenchTime != "" || t.BenchCount != 0 {
+			fail("%s: benchtime and bench_count require bench", where)
+		}
+		if t.BenchTime != "" && !validBenchTime(t.BenchTime) {
+			fail("%s: benchtime must be a duration such as \"2s\" or an iteration count such as \"500x\", got %q", where, t.BenchTime)
+		}
+		if t.BenchCount < 0 {
+			fail("%s: bench_count must not be negative, got %d", where, t.BenchCount)
+		}
+		usesInput := false
+		for _, arg := range t.Args {
+			usesInput = usesInput || strings.Contains(arg, InputPlaceholder)
+		}
+		switch {
+		case t.Input != "" && !usesInput:
+			fail("%s: input is set but no arg references %s", where, InputPlaceholder)
+		case t.Input == "" && usesInput:
+			fail("%s: args reference %s but input is not set", where, InputPlaceholder)
+		case t.Input == "" && t.ReadOnly:
+			fail("%s: read_only requires input", where)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateTenants checks the co-tenancy group p.Tasks[i]
+func validateTenants(p *Plan, i int, where string, fail func(format string, args ...any)) {
+	t := p.Tasks[i]
+	if t.Package != "" || t.Command != "" || len(t.Args) > 0 || t.Bench != "" || t.Input != "" {
+		fail("%s: a task with tenants runs other tasks and cannot set package, command, args, bench or input", where)
+	}
+	for j, tenant := range t.Tenants {
+		k := slices.IndexFunc(p.Tasks, func(other PlanTask) bool { return other.Name == tenant.Task })
+		switch {
+		case k < 0:
+			fail("%s: tenants[%d]: unknown task %q", where, j, tenant.Task)
+		case len(p.Tasks[k].Tenants) > 0:
+			fail("%s: tenants[%d]: %q is itself a co-tenancy group", where, j, tenant.Task)
+		}
+		if tenant.Count < 0 {
+			fail("%s: tenants[%d]: count must not be negative, got %d", where, j, tenant.Count)
+		}
+	}
+}
+
+// validBenchTime reports whether s is accepted by -test.benchtime
+func validBenchTime(s string) bool {
+	if n, ok := strings.CutSuffix(s, "x"); ok {
+		v, err := strconv.Atoi(n)
+		return err == nil && v > 0
+	}
+	d, err := time.ParseDuration(s)
+	return err == nil && d > 0
+}
+
+// BenchmarkConfigs converts the plan's configs to runner configs,
+// followed by the expanded sweep if there is one
+func (p *Plan) BenchmarkConfigs() ([]BenchmarkConfig, error) {
+	configs := make([]BenchmarkConfig, 0, len(p.Configs))
+	for _, c := range p.Configs {
+		gcPercent := 100
+		if c.GCPercent != nil {
+			gcPercent = *c.GCPercent
+		}
+		configs = append(configs, BenchmarkConfig{
+			Name:      c.Name,
+			MaxProcs:  c.MaxProcs,
+			MemLimit:  c.MemLimitMB,
+			GCPercent: gcPercent,
+			Timeout:   time.Duration(c.Timeout),
+
+			MemoryMax:  c.MemoryMaxMB,
+			MemoryHigh: c.MemoryHighMB,
+			CPUs:       c.CPUs,
+		})
+	}
+
+	if p.Sweep != "" {
+		sweep, err := ParseSweep(p.Sweep, p.Constraints)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, sweep.Expand()...)
+	}
+
+	return configs, nil
+}
+
+// AgentTasks converts the plan's tasks to runner tasks, applying the
+// plan-level timeout where a task does not set its own. The tenants of a
+// co-tenancy group are copies of the tasks they name.
+func (p *Plan) AgentTasks() []AgentTask {
+	tasks := make([]AgentTask, 0, len(p.Tasks))
+	byName := map[string]AgentTask{}
+	for _, t := range p.Tasks {
+		timeout := time.Duration(t.Timeout)
+		if timeout == 0 {
+			timeout = time.Duration(p.Timeout)
+		}
+		tasks = append(tasks, AgentTask{
+			Name:        t.Name,
+			Package:     t.Package,
+			Command:     t.Command,
+			Args:        t.Args,
+			Description: t.Description,
+			Timeout:     timeout,
+			Count:       t.Count,
+			Bench:       t.Bench,
+			BenchTime:   t.BenchTime,
+			BenchCount:  t.BenchCount,
+			Input:       t.Input,
+			ReadOnly:    t.ReadOnly,
+		})
+		byName[t.Name] = tasks[len(tasks)-1]
+	}
+
+	for i, t := range p.Tasks {
+		for _, tenant := range t.Tenants {
+			for n := max(tenant.Count, 1); n > 0; n-- {
+				tasks[i].Tenants = append(tasks[i].Tenants, byName[tenant.Task])
+			}
+		}
+	}
+	return tasks
+}
+
+// DefaultConfigs returns the configurations tested when no plan is given
+func DefaultConfigs() []BenchmarkConfig {
+	return []BenchmarkConfig{
+		{Name: "default", GCPercent: 100},
+		{Name: "maxprocs-1", MaxProcs: 1, GCPercent: 100},
+		{Name: "maxprocs-2", MaxProcs: 2, GCPercent: 100},
+		{Name: "maxprocs-4", MaxProcs: 4, GCPercent: 100},
+		{Name: "maxprocs-8", MaxProcs: 8, GCPercent: 100},
+		{Name: "memlimit-256", MemLimit: 256, GCPercent: 100},
+		{Name: "memlimit-512", MemLimit: 512, GCPercent: 100},
+		{Name: "memlimit-1024", MemLimit: 1024, GCPercent: 100},
+		{Name: "gc-50", GCPercent: 50},
+		{Name: "gc-200", GCPercent: 200},
+		{Name: "gc-off", GCPercent: -1},
+		{Name: "constrained", MaxProcs: 2, MemLimit: 256, GCPercent: 50},
+		{Name: "performance", MaxProcs: 8, MemLimit: 2048, GCPercent: 200},
+	}
+}
+
+// DefaultTasks returns the agent tasks run when no plan is given
+func DefaultTasks() []AgentTask {
+	return []AgentTask{
+		{
+			Name:        "code-gen",
+			Package:     "./cmd/agents/code_generator",
+			Args:        []string{"-files=100", "-lines=500", "-output={output}"},
+			Description: "Generate 100 Go files with 500 lines each (heavy workload)",
+		},
+		{
+			Name:        "file-search",
+			Package:     "./cmd/agents/file_searcher",
+			Args:        []string{"-pattern=func", "-dir={input}", "-workers=8"},
+			Description: "Search for 'func' pattern across ~300 files",
+			Input:       "./testdata",
+			ReadOnly:    true,
+		},
+		{
+			Name:        "refactor",
+			Package:     "./cmd/agents/refactor",
+			Args:        []string{"-target={input}", "-operation=rename"},
+			Description: "Rename variables across ~300 files",
+			Input:       "./testdata",
+		},
+		{
+			Name:        "ast-parser",
+			Package:     "./cmd/agents/ast_parser",
+			Args:        []string{"-target={input}"},
+			Description: "Parse ~300 Go files and extract AST information (memory-intensive)",
+			Input:       "./testdata",
+			ReadOnly:    true,
+		},
+	}
+}
@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResourceUsage is what the operating system observed about a child
+// process, independent of anything the agent reports about itself
+type ResourceUsage struct {
+	// From the child's rusage after it exited
+	PeakRSS                uint64 // bytes
+	UserCPU                time.Duration
+	SystemCPU              time.Duration
+	VoluntaryCtxSwitches   int64
+	InvoluntaryCtxSwitches int64
+	MinorPageFaults        int64
+	MajorPageFaults        int64
+	BlockInputOps          int64
+	BlockOutputOps         int64
+
+	// Sampled from /proc/<pid> while the child ran (Linux only)
+	ProcSamples     int
+	ProcPeakRSS     uint64 // VmHWM, bytes
+	ProcPeakThreads int
+	ReadChars       uint64 // rchar: bytes read via read(2) and friends
+	WriteChars      uint64 // wchar
+	ReadBytes       uint64 // read_bytes: bytes fetched from storage
+	WriteBytes      uint64 // write_bytes
+}
+
+// ProcSample is a single /proc/<pid> observation of a running agent
+type ProcSample struct {
+	PID        int
+	Elapsed    time.Duration // Since the agent started
+	RSS        uint64        // VmRSS, bytes
+	Threads    int
+	ReadChars  uint64
+	WriteChars uint64
+}
+
+// procSampler polls /proc/<pid>/status and /proc/<pid>/io until stopped.
+// The last successful sample wins for the cumulative io counters, since
+// /proc/<pid> disappears as soon as the child is reaped.
+type procSampler struct {
+	pid      int
+	start    time.Time
+	onSample func(ProcSample) // nil = none
+	stop     chan struct{}
+	done     chan struct{}
+
+	mu    sync.Mutex
+	usage ResourceUsage
+}
+
+func startProcSampler(pid int, interval time.Duration, onSample func(ProcSample)) *procSampler {
+	s := &procSampler{
+		pid:      pid,
+		start:    time.Now(),
+		onSample: onSample,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.sample()
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return s
+}
+
+// Stop ends sampling and returns the collected /proc fields
+func (s *procSampler) Stop() ResourceUsage {
+	close(s.stop)
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage
+}
+
+func (s *procSampler) sample() {
+	status, err := readProcFile(fmt.Sprintf("/proc/%d/status", s.pid))
+	if err != nil {
+		return
+	}
+	io, ioErr := readProcFile(fmt.Sprintf("/proc/%d/io", s.pid))
+	threads, _ := strconv.Atoi(status["Threads"])
+
+	s.mu.Lock()
+	s.usage.ProcSamples++
+	if hwm := parseKB(status["VmHWM"]); hwm > s.usage.ProcPeakRSS {
+		s.usage.ProcPeakRSS = hwm
+	}
+	if threads > s.usage.ProcPeakThreads {
+		s.usage.ProcPeakThreads = threads
+	}
+	if ioErr == nil {
+		s.usage.ReadChars, _ = strconv.ParseUint(io["rchar"], 10, 64)
+		s.usage.WriteChars, _ = strconv.ParseUint(io["wchar"], 10, 64)
+		s.usage.ReadBytes, _ = strconv.ParseUint(io["read_bytes"], 10, 64)
+		s.usage.WriteBytes, _ = strconv.ParseUint(io["write_bytes"], 10, 64)
+	}
+	sample := ProcSample{
+		PID:        s.pid,
+		Elapsed:    time.Since(s.start),
+		RSS:        parseKB(status["VmRSS"]),
+		Threads:    threads,
+		ReadChars:  s.usage.ReadChars,
+		WriteChars: s.usage.WriteChars,
+	}
+	s.mu.Unlock()
+
+	if s.onSample != nil {
+		s.onSample(sample)
+	}
+}
+
+// readProcFile parses a "Key: value" formatted /proc file
+func readProcFile(filename string) (map[string]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fields := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	return fields, scanner.Err()
+}
+
+// parseKB parses values such as "10240 kB" into bytes
+func parseKB(s string) uint64 {
+	v, err := strconv.ParseUint(strings.TrimSpace(strings.TrimSuffix(s, "kB")), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v * 1024
+}
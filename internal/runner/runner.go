@@ -0,0 +1,523 @@
+// Package runner builds agent binaries and runs them under Go runtime
+// configurations, measuring each run from the outside
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/agentmetrics"
+	"github.com/natalie/go-flags-eval/internal/gctrace"
+	"github.com/natalie/go-flags-eval/internal/gobench"
+)
+
+// BenchmarkConfig defines a set of Go runtime flags to test
+type BenchmarkConfig struct {
+	Name      string
+	MaxProcs  int
+	MemLimit  int64 // in MB
+	GCPercent int
+	Timeout   time.Duration `json:",omitempty"` // Per-run timeout for this config, 0 = task's
+
+	// Container-style limits enforced through a cgroup v2 leaf (-cgroup-root)
+	MemoryMax  int64   `json:",omitempty"` // memory.max in MB, 0 = unlimited
+	MemoryHigh int64   `json:",omitempty"` // memory.high in MB, 0 = unlimited
+	CPUs       float64 `json:",omitempty"` // cpu.max as a number of CPUs, 0 = unlimited
+}
+
+// BenchmarkResult stores the results of a benchmark run
+type BenchmarkResult struct {
+	Task            string
+	TaskArgs        []string
+	TaskDescription string
+	Config          BenchmarkConfig
+	Duration        time.Duration
+	MemoryAllocated uint64
+	NumGC           uint32
+	PauseTimeNs     uint64
+	ExitCode        int
+	Error           string
+	Repetition      int
+	Sequence        int // Position in the run order, warmups included
+	Resources       ResourceUsage
+	Failure         *Failure         `json:",omitempty"`
+	Cgroup          *CgroupStats     `json:",omitempty"`
+	GCTrace         []gctrace.Event  `json:",omitempty"`
+	Benchmarks      []gobench.Result `json:",omitempty"` // Go benchmark tasks only
+	Input           *InputStaging    `json:",omitempty"` // Tasks with an Input only
+	LogFile         string           `json:",omitempty"` // Agent stdout/stderr, with Runner.LogDir
+
+	// Agents run with -duration only. MemoryAllocated, NumGC and
+	// PauseTimeNs then cover the steady-state window, not the warmup.
+	SteadyState *agentmetrics.SteadyState `json:",omitempty"`
+
+	// Every runtime/metrics value the agent read at exit, histograms
+	// included
+	Runtime *agentmetrics.RuntimeMetrics `json:",omitempty"`
+
+	// Heap and GC sampled while the agent ran, see agentmetrics.StartSampler
+	Timeline []agentmetrics.Sample `json:",omitempty"`
+
+	// Co-tenancy groups only: the result of each process, and statistics
+	// across them
+	Tenants   []BenchmarkResult `json:",omitempty"`
+	CoTenancy *CoTenancyStats   `json:",omitempty"`
+}
+
+// AgentTask represents a task for an agent to perform. Tasks with a
+// Package are compiled once and the resulting binary is executed directly;
+// otherwise Command is run as given. Tasks with Bench run the Go
+// benchmarks of Package instead, from a binary built with go test -c and
+// run in the package directory, Dir.
+// Tasks with an Input get a fresh copy of that tree for every run, in
+// place of InputPlaceholder in Args, and an empty directory of their own in
+// place of OutputPlaceholder. Tasks with Tenants run all of them at
+// once instead, as separate processes.
+type AgentTask struct {
+	Name        string
+	Package     string
+	Command     string
+	Args        []string
+	Dir         string // Working directory, "" = the runner's
+	Description string
+	Timeout     time.Duration // 0 = no timeout
+	Count       int           // Repetitions per config
+
+	Bench      string `json:",omitempty"` // -test.bench regexp
+	BenchTime  string `json:",omitempty"` // -test.benchtime, e.g. "2s" or "500x"
+	BenchCount int    `json:",omitempty"` // -test.count within each run, 0 = 1
+
+	Input    string `json:",omitempty"` // Directory staged afresh for every run
+	ReadOnly bool   `json:",omitempty"` // The task never writes to Input, so hardlinks may be used
+
+	Tenants []AgentTask `json:",omitempty"` // Processes started together, one per entry
+}
+
+// Runner executes agent runs. The zero value runs without a default
+// timeout, cgroups, gctrace or /proc sampling, staging task inputs in the
+// system temp directory and passing agent output through to the console.
+type Runner struct {
+	DefaultTimeout time.Duration    // Per-run timeout when neither task nor config sets one, 0 = none
+	CgroupRoot     string           // Delegated cgroup v2 directory for per-run leaves, "" = none
+	GCTrace        bool             // Run with GODEBUG=gctrace=1 and keep the parsed cycles
+	ProcInterval   time.Duration    // How often to sample /proc/<pid>, 0 = never
+	StageDir       string           // Where task inputs are copied for each run, "" = os.TempDir()
+	LogDir         string           // Directory for a stdout/stderr log file per run, "" = the console
+	OnSample       func(ProcSample) // Called with every /proc sample while an agent runs
+
+	inputs inputManifests
+}
+
+// Run executes task once under cfg and returns its result. Failures are
+// reported in the result rather than as an error. Tasks with Tenants run
+// as a group of concurrent processes, see runGroup.
+func (r *Runner) Run(ctx context.Context, task AgentTask, cfg BenchmarkConfig) BenchmarkResult {
+	if len(task.Tenants) > 0 {
+		return r.runGroup(ctx, task, cfg)
+	}
+	p := r.prepare(ctx, task, cfg)
+	defer p.close()
+	if p.cmd == nil {
+		return p.result
+	}
+	p.oomBefore = oomKillCount()
+	p.run(r.ProcInterval, r.OnSample)
+	return p.finish()
+}
+
+// process is one agent process together with everything set up for it:
+// its metrics file, staged input, log file and cgroup leaf
+type process struct {
+	task     AgentTask
+	cfg      BenchmarkConfig
+	ctx      context.Context
+	timeout  time.Duration // Starts when run starts the process
+	cmd      *exec.Cmd     // nil if preparing failed, with the failure in result
+	result   BenchmarkResult
+	cleanups []func() // Run in reverse order by close
+
+	metricsPath    string
+	benchOutput    bytes.Buffer
+	traceCollector *gctrace.Collector
+	leaf           *cgroupLeaf
+	input          *stagedInput
+
+	started   time.Time
+	waitErr   error
+	oomBefore uint64 // System OOM kill count before the start, set by the caller
+}
+
+// prepare sets up a process running task under cfg without starting it
+func (r *Runner) prepare(ctx context.Context, task AgentTask, cfg BenchmarkConfig) *process {
+	p := &process{
+		task: task,
+		cfg:  cfg,
+		result: BenchmarkResult{
+			Task:            task.Name,
+			TaskArgs:        task.Args,
+			TaskDescription: task.Description,
+			Config:          cfg,
+		},
+	}
+	fail := func(format string, args ...any) *process {
+		p.result.Failure = &Failure{Class: FailureStart, Message: fmt.Sprintf(format, args...)}
+		p.result.Error = p.result.Failure.Message
+		return p
+	}
+
+	// Create temporary file for metrics
+	if task.Bench == "" {
+		metricsFile, err := os.CreateTemp("", "agent-metrics-*.json")
+		if err != nil {
+			return fail("creating metrics file: %v", err)
+		}
+		p.metricsPath = metricsFile.Name()
+		metricsFile.Close()
+		p.cleanups = append(p.cleanups, func() { os.Remove(p.metricsPath) })
+	}
+
+	// Prepare environment; exec keeps the last value of a duplicate
+	env := append(os.Environ(), r.flagEnv(cfg)...)
+	args := agentArgs(task, p.metricsPath)
+
+	// Give the run its own copy of the input tree, so that a task that
+	// modifies it sees the same workload under every config
+	if task.Input != "" {
+		input, err := r.stageInput(task)
+		if err != nil {
+			return fail("staging input: %v", err)
+		}
+		p.input = input
+		p.cleanups = append(p.cleanups, input.remove)
+		p.result.Input = &input.stats
+		args = substitute(args, InputPlaceholder, input.dir)
+	}
+
+	// Tasks that write files get a directory of their own, so that the
+	// tenants of a group never write into each other's output
+	if slices.ContainsFunc(args, func(arg string) bool { return strings.Contains(arg, OutputPlaceholder) }) {
+		output, err := os.MkdirTemp(r.StageDir, "output-*")
+		if err != nil {
+			return fail("creating output directory: %v", err)
+		}
+		p.cleanups = append(p.cleanups, func() {
+			if err := os.RemoveAll(output); err != nil {
+				log.Printf("Warning: failed to remove run output: %v", err)
+			}
+		})
+		args = substitute(args, OutputPlaceholder, output)
+	}
+
+	p.ctx = ctx
+	p.timeout = r.runTimeout(task, cfg)
+
+	// Agent output goes to the run's log file, or to the console
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
+	if r.LogDir != "" {
+		logFile, err := r.createLog(task, cfg)
+		if err != nil {
+			return fail("creating log file: %v", err)
+		}
+		p.cleanups = append(p.cleanups, func() { logFile.Close() })
+		p.result.LogFile = logFile.Name()
+		stdout, stderr = logFile, logFile
+	}
+
+	cmd := exec.Command(task.Command, args...)
+	cmd.Dir = task.Dir
+	cmd.Env = env
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if task.Bench != "" {
+		cmd.Stdout = io.MultiWriter(stdout, &p.benchOutput)
+	}
+
+	if r.GCTrace {
+		p.traceCollector = gctrace.NewCollector(stderr)
+		cmd.Stderr = p.traceCollector
+	}
+
+	if r.CgroupRoot != "" {
+		leaf, err := createCgroupLeaf(r.CgroupRoot, cfg)
+		if err != nil {
+			return fail("%v", err)
+		}
+		p.leaf = leaf
+		p.cleanups = append(p.cleanups, func() {
+			if err := leaf.remove(); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		})
+		leaf.apply(cmd)
+	}
+
+	p.cmd = cmd
+	return p
+}
+
+// run starts the process and waits for it to exit, sampling /proc every
+// interval if it is positive. The timeout starts here rather than in
+// prepare, so that the tenants of a group do not spend it on preparing
+// the others and waiting at the barrier.
+func (p *process) run(interval time.Duration, onSample func(ProcSample)) {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		p.ctx, cancel = context.WithTimeout(p.ctx, p.timeout)
+		p.cleanups = append(p.cleanups, cancel)
+	}
+	p.started = time.Now()
+	err := p.ctx.Err()
+	if err == nil {
+		err = p.cmd.Start()
+	}
+	if err == nil {
+		stop := context.AfterFunc(p.ctx, func() { p.cmd.Process.Kill() })
+		defer stop()
+	}
+	if err == nil && interval > 0 {
+		sampler := startProcSampler(p.cmd.Process.Pid, interval, onSample)
+		err = p.cmd.Wait()
+		p.result.Resources = sampler.Stop()
+	} else if err == nil {
+		err = p.cmd.Wait()
+	}
+	p.result.Duration = time.Since(p.started)
+	p.waitErr = err
+	applyRusage(&p.result.Resources, p.cmd.ProcessState)
+}
+
+// finish collects the results of the exited process
+func (p *process) finish() BenchmarkResult {
+	result := p.result
+	if p.traceCollector != nil {
+		result.GCTrace = p.traceCollector.Events()
+		gctrace.InferLimitTriggered(result.GCTrace, p.cfg.GCPercent, uint64(p.cfg.MemLimit)<<20)
+	}
+
+	// A run's own cgroup counts exactly its OOM kills; without one, fall
+	// back to the counters shared with everything else on the machine
+	oomKills := oomKillCount() - p.oomBefore
+	if p.leaf != nil {
+		stats := p.leaf.collect()
+		result.Cgroup = &stats
+		oomKills = stats.MemoryEventsOOMKill
+	}
+
+	failure := classifyFailure(p.ctx, p.cmd, p.waitErr, oomKills)
+	if p.input != nil {
+		if err := p.input.check(); err != nil && failure == nil {
+			failure = &Failure{Class: FailureInputModified, Message: err.Error()}
+		} else if err != nil {
+			failure.Message += "; " + err.Error()
+		}
+	}
+	if failure != nil {
+		result.Failure = failure
+		result.Error = failure.Message
+		result.ExitCode = failure.ExitCode
+		return result
+	}
+
+	if p.task.Bench != "" {
+		result.Benchmarks, _ = gobench.Parse(&p.benchOutput)
+		if len(result.Benchmarks) == 0 {
+			result.Failure = &Failure{
+				Class:   FailureMissingMetrics,
+				Message: fmt.Sprintf("no benchmark matched -test.bench=%s", p.task.Bench),
+			}
+			result.Error = result.Failure.Message
+		}
+		return result
+	}
+
+	// Read metrics from agent. A clean exit without metrics is a failure
+	// too, otherwise the zeros would be averaged into the summaries.
+	metrics, err := agentmetrics.ReadFromFile(p.metricsPath)
+	if err != nil {
+		result.Failure = &Failure{
+			Class:   FailureMissingMetrics,
+			Message: fmt.Sprintf("could not read agent metrics: %v", err),
+		}
+		result.Error = result.Failure.Message
+	} else {
+		// Use metrics from the actual agent process
+		result.MemoryAllocated = metrics.MemoryAllocated
+		result.NumGC = metrics.NumGC
+		result.PauseTimeNs = metrics.PauseTimeNs
+		result.SteadyState = metrics.SteadyState
+		result.Runtime = metrics.Runtime
+		result.Timeline = metrics.Timeline
+	}
+
+	return result
+}
+
+// close releases everything prepare set up
+func (p *process) close() {
+	for i := len(p.cleanups) - 1; i >= 0; i-- {
+		p.cleanups[i]()
+	}
+}
+
+// createLog creates the first unused <task>_<config>_<n>.log in r.LogDir
+func (r *Runner) createLog(task AgentTask, cfg BenchmarkConfig) (*os.File, error) {
+	if err := os.MkdirAll(r.LogDir, 0755); err != nil {
+		return nil, err
+	}
+	base := logName(task.Name) + "_" + logName(cfg.Name)
+	for n := 1; ; n++ {
+		path := filepath.Join(r.LogDir, fmt.Sprintf("%s_%d.log", base, n))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+}
+
+// logName makes a task or config name safe to use in a file name
+func logName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < ' ' {
+			return '-'
+		}
+		return r
+	}, name)
+}
+
+// Invocation describes how an agent process is started. Env is set on top
+// of the runner's own environment; per-run paths that only exist once a
+// run is prepared appear as MetricsPlaceholder, InputPlaceholder and
+// OutputPlaceholder.
+type Invocation struct {
+	Task    string
+	Command string
+	Args    []string
+	Dir     string // "" = the runner's working directory
+	Env     []string
+	Timeout time.Duration // 0 = none
+}
+
+// MetricsPlaceholder stands for the per-run metrics file in an Invocation
+const MetricsPlaceholder = "{metrics}"
+
+// Invocations returns how Run starts task under cfg without starting
+// anything: one entry, or one per tenant for co-tenancy groups. Tasks with
+// a Package must have Command set to their binary, see BinaryPath, and
+// benchmark tasks Dir to their package directory, see PackageDir.
+func (r *Runner) Invocations(task AgentTask, cfg BenchmarkConfig) []Invocation {
+	if len(task.Tenants) > 0 {
+		var invocations []Invocation
+		for _, tenant := range task.Tenants {
+			invocations = append(invocations, r.Invocations(tenant, cfg)...)
+		}
+		return invocations
+	}
+	return []Invocation{{
+		Task:    task.Name,
+		Command: task.Command,
+		Args:    agentArgs(task, MetricsPlaceholder),
+		Dir:     task.Dir,
+		Env:     r.flagEnv(cfg),
+		Timeout: r.runTimeout(task, cfg),
+	}}
+}
+
+// flagEnv returns the variables set for an agent under cfg on top of the
+// runner's environment
+func (r *Runner) flagEnv(cfg BenchmarkConfig) []string {
+	env := FlagEnv(cfg)
+	if r.GCTrace {
+		merged := appendGODEBUG(os.Environ(), "gctrace=1")
+		env = append(env, merged[len(merged)-1])
+	}
+	return env
+}
+
+// agentArgs adds the metrics output flag to task's args, or selects the
+// benchmarks to run
+func agentArgs(task AgentTask, metricsPath string) []string {
+	args := append([]string{}, task.Args...)
+	if task.Bench != "" {
+		return append(args, benchArgs(task)...)
+	}
+	return append(args, fmt.Sprintf("-metrics-output=%s", metricsPath))
+}
+
+// benchArgs are the test binary flags running task's benchmarks and
+// nothing else
+func benchArgs(task AgentTask) []string {
+	count := task.BenchCount
+	if count == 0 {
+		count = 1
+	}
+	args := []string{
+		"-test.run=^$",
+		"-test.bench=" + task.Bench,
+		"-test.benchmem",
+		fmt.Sprintf("-test.count=%d", count),
+	}
+	if task.BenchTime != "" {
+		args = append(args, "-test.benchtime="+task.BenchTime)
+	}
+	return args
+}
+
+// runTimeout returns the timeout for one run: the shorter of the task's
+// and the config's, or the runner's default if neither sets one
+func (r *Runner) runTimeout(task AgentTask, cfg BenchmarkConfig) time.Duration {
+	timeout := task.Timeout
+	if cfg.Timeout > 0 && (timeout == 0 || cfg.Timeout < timeout) {
+		timeout = cfg.Timeout
+	}
+	if timeout == 0 {
+		timeout = r.DefaultTimeout
+	}
+	return timeout
+}
+
+// FlagEnv returns the environment variables that apply cfg's runtime
+// flags, omitting those left at their defaults
+func FlagEnv(cfg BenchmarkConfig) []string {
+	env := []string{}
+	if cfg.MaxProcs > 0 {
+		env = append(env, fmt.Sprintf("GOMAXPROCS=%d", cfg.MaxProcs))
+	}
+	if cfg.MemLimit > 0 {
+		env = append(env, fmt.Sprintf("GOMEMLIMIT=%dMiB", cfg.MemLimit))
+	}
+	switch {
+	case cfg.GCPercent < 0:
+		env = append(env, "GOGC=off")
+	case cfg.GCPercent != 100:
+		env = append(env, fmt.Sprintf("GOGC=%d", cfg.GCPercent))
+	}
+	return env
+}
+
+// appendGODEBUG adds setting to the GODEBUG variable in env, keeping any
+// settings already present
+func appendGODEBUG(env []string, setting string) []string {
+	for i, kv := range env {
+		if value, ok := strings.CutPrefix(kv, "GODEBUG="); ok {
+			if value != "" {
+				setting = value + "," + setting
+			}
+			out := append([]string{}, env[:i]...)
+			out = append(out, env[i+1:]...)
+			return append(out, "GODEBUG="+setting)
+		}
+	}
+	return append(env, "GODEBUG="+setting)
+}
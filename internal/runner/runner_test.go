@@ -0,0 +1,35 @@
+package runner
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestRunTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run")
+	}
+	r := &Runner{}
+	cfg := BenchmarkConfig{Name: "default", Timeout: 200 * time.Millisecond}
+	// The metrics flag the runner appends becomes $0
+	slow := AgentTask{Name: "slow", Command: "sh", Args: []string{"-c", "exec sleep 5"}}
+	quick := AgentTask{Name: "quick", Command: "sh", Args: []string{"-c", "exit 3"}}
+
+	result := r.Run(context.Background(), slow, cfg)
+	if result.Failure == nil || result.Failure.Class != FailureTimeout {
+		t.Errorf("slow run failed with %+v, want a timeout", result.Failure)
+	}
+
+	// Time spent between preparing and starting, as tenants spend it at
+	// the barrier, does not count against the timeout
+	p := r.prepare(context.Background(), quick, cfg)
+	defer p.close()
+	time.Sleep(300 * time.Millisecond)
+	p.run(0, nil)
+	result = p.finish()
+	if result.Failure == nil || result.Failure.Class == FailureTimeout || result.ExitCode != 3 {
+		t.Errorf("quick run failed with %+v, want exit code 3", result.Failure)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package runner
+
+import "os"
+
+// applyRusage is a no-op on platforms without rusage
+func applyRusage(usage *ResourceUsage, state *os.ProcessState) {}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package runner
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// applyRusage copies the child's rusage into usage
+func applyRusage(usage *ResourceUsage, state *os.ProcessState) {
+	if state == nil {
+		return
+	}
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return
+	}
+
+	// ru_maxrss is reported in bytes on Darwin and in KiB elsewhere
+	usage.PeakRSS = uint64(ru.Maxrss)
+	if runtime.GOOS != "darwin" {
+		usage.PeakRSS *= 1024
+	}
+	usage.UserCPU = time.Duration(ru.Utime.Nano())
+	usage.SystemCPU = time.Duration(ru.Stime.Nano())
+	usage.VoluntaryCtxSwitches = int64(ru.Nvcsw)
+	usage.InvoluntaryCtxSwitches = int64(ru.Nivcsw)
+	usage.MinorPageFaults = int64(ru.Minflt)
+	usage.MajorPageFaults = int64(ru.Majflt)
+	usage.BlockInputOps = int64(ru.Inblock)
+	usage.BlockOutputOps = int64(ru.Oublock)
+}
@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// RunOrder decides in which order the runs of a task are executed.
+// Running each config back-to-back lets slow drift, such as a heating CPU
+// or a filling page cache, favor whichever configs come first.
+type RunOrder string
+
+const (
+	OrderSequential RunOrder = "sequential"  // All repetitions of a config, then the next config
+	OrderRoundRobin RunOrder = "round-robin" // One repetition of every config per round
+	OrderShuffle    RunOrder = "shuffle"     // A seeded random permutation of all runs
+)
+
+func ParseRunOrder(s string) (RunOrder, error) {
+	switch order := RunOrder(s); order {
+	case OrderSequential, OrderRoundRobin, OrderShuffle:
+		return order, nil
+	}
+	return "", fmt.Errorf("unknown order %q (want sequential, round-robin or shuffle)", s)
+}
+
+// Schedule records how runs were ordered, so that a sweep can be repeated
+// in exactly the same order
+type Schedule struct {
+	Order  RunOrder
+	Seed   int64 `json:",omitempty"` // Shuffle seed
+	Warmup int   // Discarded runs before the measured ones, per task/config pair
+}
+
+// ScheduledRun is one execution of a task under a config. Warmup runs are
+// numbered separately from the measured repetitions.
+type ScheduledRun struct {
+	Task   AgentTask
+	Config BenchmarkConfig
+	Rep    int
+	Warmup bool
+}
+
+// Layout lays out every run of every task. Tasks are kept in their own
+// blocks since they are never compared with each other. In sequential
+// order each config's warmups run right before it; otherwise all warmups
+// of a task come before its first measured run.
+func (s Schedule) Layout(tasks []AgentTask, configs []BenchmarkConfig) []ScheduledRun {
+	rng := rand.New(rand.NewSource(s.Seed))
+
+	runs := []ScheduledRun{}
+	for _, task := range tasks {
+		var warmups, measured []ScheduledRun
+		for w := 1; w <= s.Warmup; w++ {
+			for _, cfg := range configs {
+				warmups = append(warmups, ScheduledRun{task, cfg, w, true})
+			}
+		}
+
+		switch s.Order {
+		case OrderRoundRobin, OrderShuffle:
+			for rep := 1; rep <= task.Count; rep++ {
+				for _, cfg := range configs {
+					measured = append(measured, ScheduledRun{task, cfg, rep, false})
+				}
+			}
+		default:
+			// Sequential keeps each config's warmups directly in front of it
+			warmups = nil
+			for _, cfg := range configs {
+				for w := 1; w <= s.Warmup; w++ {
+					measured = append(measured, ScheduledRun{task, cfg, w, true})
+				}
+				for rep := 1; rep <= task.Count; rep++ {
+					measured = append(measured, ScheduledRun{task, cfg, rep, false})
+				}
+			}
+		}
+
+		if s.Order == OrderShuffle {
+			rng.Shuffle(len(warmups), func(i, j int) { warmups[i], warmups[j] = warmups[j], warmups[i] })
+			rng.Shuffle(len(measured), func(i, j int) { measured[i], measured[j] = measured[j], measured[i] })
+			renumber(warmups)
+			renumber(measured)
+		}
+
+		runs = append(runs, warmups...)
+		runs = append(runs, measured...)
+	}
+	return runs
+}
+
+// renumber restores ascending repetition numbers per config after a
+// shuffle, so that repetition n is always the n-th run of its config
+func renumber(runs []ScheduledRun) {
+	seen := map[string]int{}
+	for i := range runs {
+		seen[runs[i].Config.Name]++
+		runs[i].Rep = seen[runs[i].Config.Name]
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package runner
+
+import "os"
+
+// exitSignal always returns "" on platforms without POSIX signals
+func exitSignal(state *os.ProcessState) string {
+	return ""
+}
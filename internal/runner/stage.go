@@ -0,0 +1,296 @@
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InputPlaceholder is replaced in a task's Args by the path of the run's
+// private copy of the task's Input
+const InputPlaceholder = "{input}"
+
+// OutputPlaceholder is replaced in a task's Args by the path of an empty
+// directory created for the run and removed after it
+const OutputPlaceholder = "{output}"
+
+// StageMethod is how the files of an input tree were staged
+type StageMethod string
+
+const (
+	StageReflink  StageMethod = "reflink"  // Copy-on-write clones (FICLONE), same filesystem only
+	StageHardlink StageMethod = "hardlink" // Read-only tasks only, same filesystem only
+	StageCopy     StageMethod = "copy"     // Full byte copies
+)
+
+// InputStaging describes the private copy of a task's input tree made for
+// one run
+type InputStaging struct {
+	Source    string
+	Method    StageMethod
+	Files     int
+	Bytes     int64
+	StageTime time.Duration // Copying and verifying, outside the measured run
+}
+
+// Manifest maps the slash-separated paths of the files in a tree to the
+// SHA-256 checksums of their contents (symlinks: of their targets)
+type Manifest map[string]string
+
+// BuildManifest checksums every file below root
+func BuildManifest(root string) (Manifest, error) {
+	m := Manifest{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			io.WriteString(h, "symlink:"+target)
+		} else {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+		m[filepath.ToSlash(rel)] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	return m, err
+}
+
+// Diff describes how other differs from m, or returns "" if the trees are
+// identical
+func (m Manifest) Diff(other Manifest) string {
+	var changed, missing, added []string
+	for path, sum := range m {
+		switch otherSum, ok := other[path]; {
+		case !ok:
+			missing = append(missing, path)
+		case otherSum != sum:
+			changed = append(changed, path)
+		}
+	}
+	for path := range other {
+		if _, ok := m[path]; !ok {
+			added = append(added, path)
+		}
+	}
+
+	parts := []string{}
+	for _, group := range []struct {
+		what  string
+		paths []string
+	}{{"changed", changed}, {"missing", missing}, {"added", added}} {
+		if len(group.paths) == 0 {
+			continue
+		}
+		sort.Strings(group.paths)
+		examples := group.paths
+		if len(examples) > 3 {
+			examples = append(examples[:3:3], "...")
+		}
+		parts = append(parts, fmt.Sprintf("%d %s (%s)", len(group.paths), group.what, strings.Join(examples, ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// inputManifests caches the manifest of every source tree taken before
+// its first run, so that every later run is checked against the original
+type inputManifests struct {
+	mu        sync.Mutex
+	manifests map[string]Manifest
+}
+
+func (c *inputManifests) get(source string) (Manifest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if m, ok := c.manifests[source]; ok {
+		return m, nil
+	}
+	m, err := BuildManifest(source)
+	if err != nil {
+		return nil, err
+	}
+	if c.manifests == nil {
+		c.manifests = map[string]Manifest{}
+	}
+	c.manifests[source] = m
+	return m, nil
+}
+
+// stagedInput is a private copy of a task's input tree
+type stagedInput struct {
+	dir      string // Root of the copy
+	source   string
+	manifest Manifest // Of the source before the first run
+	readOnly bool
+	stats    InputStaging
+}
+
+// stageInput copies task's input tree into a fresh directory below
+// r.StageDir and verifies the copy against
+// the source manifest. Read-only tasks may share the source files through
+// hardlinks; every task gets copy-on-write clones where the filesystem
+// supports them.
+func (r *Runner) stageInput(task AgentTask) (*stagedInput, error) {
+	start := time.Now()
+	source := filepath.Clean(task.Input)
+	manifest, err := r.inputs.get(source)
+	if err != nil {
+		return nil, fmt.Errorf("checksumming %s: %w", source, err)
+	}
+
+	dir, err := os.MkdirTemp(r.StageDir, "input-*")
+	if err != nil {
+		return nil, err
+	}
+	s := &stagedInput{dir: dir, source: source, manifest: manifest, readOnly: task.ReadOnly}
+	s.stats = InputStaging{Source: source, Method: StageReflink}
+
+	err = filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+		switch {
+		case d.IsDir():
+			if rel == "." {
+				return nil
+			}
+			return os.Mkdir(target, 0755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			s.stats.Files++
+			return os.Symlink(link, target)
+		case !d.Type().IsRegular():
+			return nil
+		}
+		n, err := s.stageFile(path, target)
+		s.stats.Files++
+		s.stats.Bytes += n
+		return err
+	})
+	if err == nil {
+		err = s.verify(s.dir, "staged copy")
+	}
+	if err != nil {
+		s.remove()
+		return nil, err
+	}
+	s.stats.StageTime = time.Since(start)
+	return s, nil
+}
+
+// stageFile stages one file with the current method, falling back to the
+// next weaker one for this and all later files once a method fails
+func (s *stagedInput) stageFile(src, dst string) (int64, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return 0, err
+	}
+	if s.stats.Method == StageReflink {
+		if err := reflinkFile(src, dst, info.Mode().Perm()); err == nil {
+			return info.Size(), nil
+		}
+		s.stats.Method = StageHardlink
+		if !s.readOnly {
+			s.stats.Method = StageCopy
+		}
+	}
+	if s.stats.Method == StageHardlink {
+		if err := os.Link(src, dst); err == nil {
+			return info.Size(), nil
+		}
+		s.stats.Method = StageCopy
+	}
+	return info.Size(), copyFile(src, dst, info.Mode().Perm())
+}
+
+// verify compares the tree at root with the source manifest
+func (s *stagedInput) verify(root, what string) error {
+	m, err := BuildManifest(root)
+	if err != nil {
+		return fmt.Errorf("checksumming %s: %w", what, err)
+	}
+	if diff := s.manifest.Diff(m); diff != "" {
+		return fmt.Errorf("%s differs from the manifest of %s: %s", what, s.source, diff)
+	}
+	return nil
+}
+
+// check runs after the agent exited. The source must be untouched, and a
+// read-only task must not have written to its copy either, which with
+// hardlinks would have modified the source as well.
+func (s *stagedInput) check() error {
+	if err := s.verify(s.source, "source"); err != nil {
+		return err
+	}
+	if s.readOnly {
+		if err := s.verify(s.dir, "read-only copy"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *stagedInput) remove() {
+	if err := os.RemoveAll(s.dir); err != nil {
+		log.Printf("Warning: failed to remove staged input: %v", err)
+	}
+}
+
+// substitute replaces placeholder in args with dir
+func substitute(args []string, placeholder, dir string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = strings.ReplaceAll(arg, placeholder, dir)
+	}
+	return out
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
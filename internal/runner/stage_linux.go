@@ -0,0 +1,31 @@
+package runner
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl, _IOW(0x94, 9, int)
+const ficlone = 0x40049409
+
+// reflinkFile clones src into a new file dst sharing its extents
+// copy-on-write, on filesystems that support it (Btrfs, XFS, bcachefs)
+func reflinkFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, out.Fd(), ficlone, in.Fd())
+	out.Close()
+	if errno != 0 {
+		os.Remove(dst)
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package runner
+
+import (
+	"errors"
+	"io/fs"
+)
+
+func reflinkFile(src, dst string, perm fs.FileMode) error {
+	return errors.ErrUnsupported
+}
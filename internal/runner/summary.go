@@ -0,0 +1,252 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/natalie/go-flags-eval/internal/agentmetrics"
+	"github.com/natalie/go-flags-eval/internal/stats"
+)
+
+// BenchmarkSummary aggregates repeated runs of one task/config pair
+type BenchmarkSummary struct {
+	Task            string
+	Config          BenchmarkConfig
+	Runs            int
+	Failures        int
+	Duration        stats.Summary // nanoseconds
+	MemoryAllocated stats.Summary // bytes
+	NumGC           stats.Summary
+	PauseTimeNs     stats.Summary
+	PeakRSS         stats.Summary       // bytes
+	CPUTime         stats.Summary       // user+system nanoseconds
+	Benchmarks      []BenchSummary      `json:",omitempty"` // Go benchmark tasks only
+	CoTenancy       *CoTenancySummary   `json:",omitempty"` // Co-tenancy groups only
+	SteadyState     *SteadyStateSummary `json:",omitempty"` // Agents run with -duration only
+	Runtime         *RuntimeSummary     `json:",omitempty"` // Agents reporting runtime/metrics only
+}
+
+// RuntimeSummary aggregates what the runtime/metrics of the successful
+// runs say about the GC's cost
+type RuntimeSummary struct {
+	GCCPUFraction   stats.Summary // GC share of GOMAXPROCS × wall time
+	HeapGoal        stats.Summary // bytes, at exit
+	GCPauseP99      stats.Summary // nanoseconds
+	SchedLatencyP99 stats.Summary // nanoseconds, runnable until running
+	LimiterRuns     int           // Runs in which the GC CPU limiter kicked in
+}
+
+// SteadyStateSummary aggregates the throughput and per-iteration latency
+// percentiles of -duration runs over the successful runs
+type SteadyStateSummary struct {
+	OpsPerSec   stats.Summary
+	LatencyP50  stats.Summary // nanoseconds
+	LatencyP90  stats.Summary // nanoseconds
+	LatencyP99  stats.Summary // nanoseconds
+	LatencyP999 stats.Summary // nanoseconds
+}
+
+// CoTenancySummary aggregates the per-run co-tenancy statistics of a
+// group over its successful runs
+type CoTenancySummary struct {
+	Processes   int
+	Throughput  stats.Summary // processes per second
+	DurationP50 stats.Summary // nanoseconds
+	DurationP99 stats.Summary // nanoseconds
+	DurationMax stats.Summary // nanoseconds
+	StartSkew   stats.Summary // nanoseconds
+}
+
+// BenchSummary aggregates one Go benchmark over every result line of the
+// successful runs, so -test.count lines and repetitions pool together
+type BenchSummary struct {
+	Name        string
+	NsPerOp     stats.Summary
+	BytesPerOp  stats.Summary
+	AllocsPerOp stats.Summary
+	Metrics     map[string]stats.Summary `json:",omitempty"` // Custom metrics by unit
+}
+
+// Summarize computes per-pair statistics over the successful samples
+func Summarize(task string, cfg BenchmarkConfig, samples []BenchmarkResult) BenchmarkSummary {
+	summary := BenchmarkSummary{
+		Task:   task,
+		Config: cfg,
+		Runs:   len(samples),
+	}
+
+	var durations, memory, numGC, pauses, peakRSS, cpuTime []float64
+	for _, r := range samples {
+		if r.Error != "" {
+			summary.Failures++
+			continue
+		}
+		durations = append(durations, float64(r.Duration))
+		memory = append(memory, float64(r.MemoryAllocated))
+		numGC = append(numGC, float64(r.NumGC))
+		pauses = append(pauses, float64(r.PauseTimeNs))
+		peakRSS = append(peakRSS, float64(r.Resources.PeakRSS))
+		cpuTime = append(cpuTime, float64(r.Resources.UserCPU+r.Resources.SystemCPU))
+	}
+
+	summary.Duration = stats.Summarize(durations)
+	summary.MemoryAllocated = stats.Summarize(memory)
+	summary.NumGC = stats.Summarize(numGC)
+	summary.PauseTimeNs = stats.Summarize(pauses)
+	summary.PeakRSS = stats.Summarize(peakRSS)
+	summary.CPUTime = stats.Summarize(cpuTime)
+	summary.Benchmarks = summarizeBenchmarks(samples)
+	summary.CoTenancy = summarizeCoTenancy(samples)
+	summary.SteadyState = summarizeSteadyState(samples)
+	summary.Runtime = summarizeRuntime(samples)
+
+	return summary
+}
+
+func summarizeSteadyState(samples []BenchmarkResult) *SteadyStateSummary {
+	var ops, p50, p90, p99, p999 []float64
+	for _, r := range samples {
+		if r.Error != "" || r.SteadyState == nil {
+			continue
+		}
+		ops = append(ops, r.SteadyState.OpsPerSec)
+		p50 = append(p50, float64(r.SteadyState.LatencyP50))
+		p90 = append(p90, float64(r.SteadyState.LatencyP90))
+		p99 = append(p99, float64(r.SteadyState.LatencyP99))
+		p999 = append(p999, float64(r.SteadyState.LatencyP999))
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	return &SteadyStateSummary{
+		OpsPerSec:   stats.Summarize(ops),
+		LatencyP50:  stats.Summarize(p50),
+		LatencyP90:  stats.Summarize(p90),
+		LatencyP99:  stats.Summarize(p99),
+		LatencyP999: stats.Summarize(p999),
+	}
+}
+
+func summarizeRuntime(samples []BenchmarkResult) *RuntimeSummary {
+	var s *RuntimeSummary
+	var gcCPU, heapGoal, pauseP99, schedP99 []float64
+	for _, r := range samples {
+		rm := r.Runtime
+		if r.Error != "" || rm == nil {
+			continue
+		}
+		if s == nil {
+			s = &RuntimeSummary{}
+		}
+		if rm.LimiterEnabled() {
+			s.LimiterRuns++
+		}
+		gcCPU = append(gcCPU, rm.GCCPUFraction())
+		heapGoal = append(heapGoal, float64(rm.Uint64[agentmetrics.MetricHeapGoal]))
+		pauseP99 = append(pauseP99, rm.GCPauses().Quantile(0.99)*1e9)
+		schedP99 = append(schedP99, rm.Histograms[agentmetrics.MetricSchedLatency].Quantile(0.99)*1e9)
+	}
+	if s == nil {
+		return nil
+	}
+	s.GCCPUFraction = stats.Summarize(gcCPU)
+	s.HeapGoal = stats.Summarize(heapGoal)
+	s.GCPauseP99 = stats.Summarize(pauseP99)
+	s.SchedLatencyP99 = stats.Summarize(schedP99)
+	return s
+}
+
+func summarizeCoTenancy(samples []BenchmarkResult) *CoTenancySummary {
+	var s *CoTenancySummary
+	var throughput, p50, p99, longest, skew []float64
+	for _, r := range samples {
+		if r.Error != "" || r.CoTenancy == nil {
+			continue
+		}
+		if s == nil {
+			s = &CoTenancySummary{Processes: r.CoTenancy.Processes}
+		}
+		throughput = append(throughput, r.CoTenancy.Throughput)
+		p50 = append(p50, float64(r.CoTenancy.DurationP50))
+		p99 = append(p99, float64(r.CoTenancy.DurationP99))
+		longest = append(longest, float64(r.CoTenancy.DurationMax))
+		skew = append(skew, float64(r.CoTenancy.StartSkew))
+	}
+	if s == nil {
+		return nil
+	}
+	s.Throughput = stats.Summarize(throughput)
+	s.DurationP50 = stats.Summarize(p50)
+	s.DurationP99 = stats.Summarize(p99)
+	s.DurationMax = stats.Summarize(longest)
+	s.StartSkew = stats.Summarize(skew)
+	return s
+}
+
+func summarizeBenchmarks(samples []BenchmarkResult) []BenchSummary {
+	type values struct {
+		nsPerOp, bytesPerOp, allocsPerOp []float64
+		metrics                          map[string][]float64
+	}
+	names := []string{}
+	byName := map[string]*values{}
+	for _, r := range samples {
+		if r.Error != "" {
+			continue
+		}
+		for _, b := range r.Benchmarks {
+			v, ok := byName[b.Name]
+			if !ok {
+				v = &values{metrics: map[string][]float64{}}
+				byName[b.Name] = v
+				names = append(names, b.Name)
+			}
+			v.nsPerOp = append(v.nsPerOp, b.NsPerOp)
+			v.bytesPerOp = append(v.bytesPerOp, b.BytesPerOp)
+			v.allocsPerOp = append(v.allocsPerOp, b.AllocsPerOp)
+			for unit, m := range b.Metrics {
+				v.metrics[unit] = append(v.metrics[unit], m)
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+	summaries := make([]BenchSummary, 0, len(names))
+	for _, name := range names {
+		v := byName[name]
+		s := BenchSummary{
+			Name:        name,
+			NsPerOp:     stats.Summarize(v.nsPerOp),
+			BytesPerOp:  stats.Summarize(v.bytesPerOp),
+			AllocsPerOp: stats.Summarize(v.allocsPerOp),
+		}
+		if len(v.metrics) > 0 {
+			s.Metrics = map[string]stats.Summary{}
+			for unit, values := range v.metrics {
+				s.Metrics[unit] = stats.Summarize(values)
+			}
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+// String formats the summary as a single progress line
+func (s BenchmarkSummary) String() string {
+	if s.Duration.N == 0 {
+		return fmt.Sprintf("all %d runs failed", s.Runs)
+	}
+	return fmt.Sprintf("Duration: %v ±%.1f%% (median %v, min %v, max %v), Memory: %.2f MB ±%.1f%%, Peak RSS: %.2f MB, GC runs: %.1f (n=%d)",
+		time.Duration(s.Duration.Mean).Round(time.Microsecond),
+		s.Duration.RelativeCI()*100,
+		time.Duration(s.Duration.Median).Round(time.Microsecond),
+		time.Duration(s.Duration.Min).Round(time.Microsecond),
+		time.Duration(s.Duration.Max).Round(time.Microsecond),
+		s.MemoryAllocated.Mean/(1024*1024),
+		s.MemoryAllocated.RelativeCI()*100,
+		s.PeakRSS.Mean/(1024*1024),
+		s.NumGC.Mean,
+		s.Duration.N)
+}
@@ -0,0 +1,435 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A sweep expression lists values for one or more runtime flags and
+// expands into the cross product of all of them:
+//
+//	GOMAXPROCS=1,2,4,8 GOGC=50..400:step50 GOMEMLIMIT=256MiB,512MiB,off
+//
+// Each value list is a comma-separated mix of single values and ranges.
+// Ranges are lo..hi with an optional :stepN (additive, default step 1)
+// or :xN (multiplicative) suffix. GOGC accepts "off" for -1, GOMEMLIMIT
+// accepts "off" for no limit and the B/KiB/MiB/GiB/TiB suffixes that the
+// runtime itself accepts, and GOMAXPROCS accepts "default".
+//
+// Constraints prune the expanded points. Each is a comparison such as
+// GOGC<=200 or GOMEMLIMIT!=off, or an implication A => B between two
+// comparisons. Ordered comparisons (<, <=, >, >=) never match a flag that
+// is unset (GOMAXPROCS=default, GOMEMLIMIT=off, GOGC=off).
+
+// sweepKeys are the flags a sweep may vary, in canonical naming order
+var sweepKeys = []string{"GOMAXPROCS", "GOMEMLIMIT", "GOGC"}
+
+type sweepDim struct {
+	key    string
+	values []int64
+}
+
+// Sweep is a parsed sweep expression
+type Sweep struct {
+	dims        []sweepDim
+	constraints []constraint
+}
+
+// ParseSweep parses a sweep expression and its constraints
+func ParseSweep(expr string, constraints []string) (*Sweep, error) {
+	sweep := &Sweep{}
+	seen := map[string]bool{}
+
+	for _, assignment := range strings.Fields(expr) {
+		key, list, ok := strings.Cut(assignment, "=")
+		if !ok {
+			return nil, fmt.Errorf("sweep %q: expected KEY=values", assignment)
+		}
+		key = strings.ToUpper(key)
+		if !isSweepKey(key) {
+			return nil, fmt.Errorf("sweep %q: unknown flag %s (want one of %s)", assignment, key, strings.Join(sweepKeys, ", "))
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("sweep %q: %s given more than once", assignment, key)
+		}
+		seen[key] = true
+
+		values, err := parseValueList(key, list)
+		if err != nil {
+			return nil, fmt.Errorf("sweep %q: %w", assignment, err)
+		}
+		sweep.dims = append(sweep.dims, sweepDim{key: key, values: values})
+	}
+
+	if len(sweep.dims) == 0 {
+		return nil, errors.New("sweep: no flags given")
+	}
+
+	for _, c := range constraints {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		parsed, err := parseConstraint(c)
+		if err != nil {
+			return nil, fmt.Errorf("constraint %q: %w", c, err)
+		}
+		sweep.constraints = append(sweep.constraints, parsed)
+	}
+
+	return sweep, nil
+}
+
+// Expand returns one config per point of the cross product that satisfies
+// every constraint. Points that normalize to the same flags are emitted
+// once.
+func (s *Sweep) Expand() []BenchmarkConfig {
+	configs := []BenchmarkConfig{}
+	seen := map[BenchmarkConfig]bool{}
+
+	point := BenchmarkConfig{GCPercent: 100}
+	var walk func(i int)
+	walk = func(i int) {
+		if i == len(s.dims) {
+			for _, c := range s.constraints {
+				if !c.match(point) {
+					return
+				}
+			}
+			cfg := point
+			cfg.Name = configName(cfg)
+			if !seen[cfg] {
+				seen[cfg] = true
+				configs = append(configs, cfg)
+			}
+			return
+		}
+		dim := s.dims[i]
+		for _, v := range dim.values {
+			setSweepValue(&point, dim.key, v)
+			walk(i + 1)
+		}
+	}
+	walk(0)
+
+	return configs
+}
+
+// Size returns the number of points before constraints are applied
+func (s *Sweep) Size() int {
+	n := 1
+	for _, d := range s.dims {
+		n *= len(d.values)
+	}
+	return n
+}
+
+// Shape returns the number of values of each flag, in the order the
+// flags appear in the expression. Together with Point it lets a search
+// walk the sweep without expanding it.
+func (s *Sweep) Shape() []int {
+	shape := make([]int, len(s.dims))
+	for i, d := range s.dims {
+		shape[i] = len(d.values)
+	}
+	return shape
+}
+
+// Point returns the config at the given value index of each flag, and
+// whether it satisfies every constraint
+func (s *Sweep) Point(indices []int) (BenchmarkConfig, bool) {
+	cfg := BenchmarkConfig{GCPercent: 100}
+	for i, d := range s.dims {
+		setSweepValue(&cfg, d.key, d.values[indices[i]])
+	}
+	cfg.Name = configName(cfg)
+	for _, c := range s.constraints {
+		if !c.match(cfg) {
+			return cfg, false
+		}
+	}
+	return cfg, true
+}
+
+// configName derives a name from the flags that differ from the defaults,
+// matching the names of the built-in configs for single-flag changes
+func configName(cfg BenchmarkConfig) string {
+	parts := []string{}
+	if cfg.MaxProcs > 0 {
+		parts = append(parts, fmt.Sprintf("maxprocs-%d", cfg.MaxProcs))
+	}
+	if cfg.MemLimit > 0 {
+		parts = append(parts, fmt.Sprintf("memlimit-%d", cfg.MemLimit))
+	}
+	switch {
+	case cfg.GCPercent < 0:
+		parts = append(parts, "gc-off")
+	case cfg.GCPercent != 100:
+		parts = append(parts, fmt.Sprintf("gc-%d", cfg.GCPercent))
+	}
+	if len(parts) == 0 {
+		return "default"
+	}
+	return strings.Join(parts, "_")
+}
+
+func isSweepKey(key string) bool {
+	for _, k := range sweepKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func setSweepValue(cfg *BenchmarkConfig, key string, v int64) {
+	switch key {
+	case "GOMAXPROCS":
+		cfg.MaxProcs = int(v)
+	case "GOMEMLIMIT":
+		cfg.MemLimit = v
+	case "GOGC":
+		cfg.GCPercent = int(v)
+	}
+}
+
+func getSweepValue(cfg BenchmarkConfig, key string) int64 {
+	switch key {
+	case "GOMAXPROCS":
+		return int64(cfg.MaxProcs)
+	case "GOMEMLIMIT":
+		return cfg.MemLimit
+	default:
+		return int64(cfg.GCPercent)
+	}
+}
+
+// isUnset reports whether v means "flag not set" for key
+func isUnset(key string, v int64) bool {
+	switch key {
+	case "GOMAXPROCS", "GOMEMLIMIT":
+		return v == 0
+	default:
+		return v < 0
+	}
+}
+
+func parseValueList(key, list string) ([]int64, error) {
+	values := []int64{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return nil, errors.New("empty value")
+		}
+		if lo, rest, ok := strings.Cut(item, ".."); ok {
+			expanded, err := parseRange(key, lo, rest)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", item, err)
+			}
+			values = append(values, expanded...)
+			continue
+		}
+		v, err := parseSweepValue(key, item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// parseRange expands lo..hi[:stepN|:xN]
+func parseRange(key, loStr, rest string) ([]int64, error) {
+	hiStr, stepStr, hasStep := strings.Cut(rest, ":")
+
+	lo, err := parseSweepValue(key, loStr)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := parseSweepValue(key, hiStr)
+	if err != nil {
+		return nil, err
+	}
+	if isUnset(key, lo) || isUnset(key, hi) {
+		return nil, errors.New("range bounds must be concrete values")
+	}
+	if hi < lo {
+		return nil, fmt.Errorf("range upper bound is below lower bound")
+	}
+
+	var next func(int64) int64
+	switch {
+	case !hasStep:
+		next = func(v int64) int64 { return v + 1 }
+	case strings.HasPrefix(stepStr, "step"):
+		step, err := parseSweepValue(key, strings.TrimPrefix(stepStr, "step"))
+		if err != nil || step <= 0 {
+			return nil, fmt.Errorf("invalid step %q", stepStr)
+		}
+		next = func(v int64) int64 { return v + step }
+	case strings.HasPrefix(stepStr, "x"):
+		factor, err := strconv.ParseInt(strings.TrimPrefix(stepStr, "x"), 10, 64)
+		if err != nil || factor < 2 {
+			return nil, fmt.Errorf("invalid factor %q", stepStr)
+		}
+		next = func(v int64) int64 { return v * factor }
+	default:
+		return nil, fmt.Errorf("invalid range suffix %q (want stepN or xN)", stepStr)
+	}
+
+	const maxRangeValues = 1000
+	values := []int64{}
+	for v := lo; v <= hi; v = next(v) {
+		values = append(values, v)
+		if len(values) > maxRangeValues {
+			return nil, fmt.Errorf("range expands to more than %d values", maxRangeValues)
+		}
+	}
+	return values, nil
+}
+
+// parseSweepValue parses a single value into the unit BenchmarkConfig
+// uses for key: a count for GOMAXPROCS, MiB for GOMEMLIMIT and a
+// percentage for GOGC
+func parseSweepValue(key, s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	switch key {
+	case "GOMAXPROCS":
+		if s == "default" {
+			return 0, nil
+		}
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v < 1 {
+			return 0, fmt.Errorf("invalid GOMAXPROCS value %q", s)
+		}
+		return v, nil
+	case "GOMEMLIMIT":
+		if s == "off" {
+			return 0, nil
+		}
+		bytes, err := ParseByteSize(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid GOMEMLIMIT value %q: %w", s, err)
+		}
+		if bytes <= 0 || bytes%(1024*1024) != 0 {
+			return 0, fmt.Errorf("invalid GOMEMLIMIT value %q: must be a positive whole number of MiB", s)
+		}
+		return bytes / (1024 * 1024), nil
+	default:
+		if s == "off" {
+			return -1, nil
+		}
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid GOGC value %q", s)
+		}
+		return v, nil
+	}
+}
+
+// ParseByteSize parses a size using the suffixes accepted by GOMEMLIMIT
+func ParseByteSize(s string) (int64, error) {
+	size := s
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"TiB", 1 << 40},
+		{"GiB", 1 << 30},
+		{"MiB", 1 << 20},
+		{"KiB", 1 << 10},
+		{"B", 1},
+	}
+	mult := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSuffix(s, u.suffix)
+			mult = u.mult
+			break
+		}
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if v > math.MaxInt64/mult || v < math.MinInt64/mult {
+		return 0, fmt.Errorf("size %q does not fit into 64 bits", size)
+	}
+	return v * mult, nil
+}
+
+// constraint is a single comparison, or an implication between two
+type constraint struct {
+	key   string
+	op    string
+	value int64
+	cond  *constraint // for implications, cond => this
+}
+
+func (c constraint) match(cfg BenchmarkConfig) bool {
+	if c.cond != nil && !c.cond.match(cfg) {
+		return true
+	}
+
+	v := getSweepValue(cfg, c.key)
+	switch c.op {
+	case "=":
+		return v == c.value
+	case "!=":
+		return v != c.value
+	}
+
+	if isUnset(c.key, v) || isUnset(c.key, c.value) {
+		return false
+	}
+	switch c.op {
+	case "<":
+		return v < c.value
+	case "<=":
+		return v <= c.value
+	case ">":
+		return v > c.value
+	default:
+		return v >= c.value
+	}
+}
+
+func parseConstraint(s string) (constraint, error) {
+	if cond, then, ok := strings.Cut(s, "=>"); ok {
+		c, err := parseComparison(cond)
+		if err != nil {
+			return constraint{}, err
+		}
+		t, err := parseComparison(then)
+		if err != nil {
+			return constraint{}, err
+		}
+		t.cond = &c
+		return t, nil
+	}
+	return parseComparison(s)
+}
+
+func parseComparison(s string) (constraint, error) {
+	s = strings.TrimSpace(s)
+	// Longest operators first so "<=" is not read as "<"
+	for _, op := range []string{"!=", "<=", ">=", "=", "<", ">"} {
+		key, value, ok := strings.Cut(s, op)
+		if !ok {
+			continue
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if !isSweepKey(key) {
+			return constraint{}, fmt.Errorf("unknown flag %q", key)
+		}
+		v, err := parseSweepValue(key, value)
+		if err != nil {
+			return constraint{}, err
+		}
+		return constraint{key: key, op: op, value: v}, nil
+	}
+	return constraint{}, errors.New("expected a comparison such as GOGC<=200")
+}
@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"512B", 512},
+		{"4KiB", 4 << 10},
+		{"256MiB", 256 << 20},
+		{"2GiB", 2 << 30},
+		{"1TiB", 1 << 40},
+		{"8388607TiB", 8388607 << 40},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseByteSizeErrors(t *testing.T) {
+	for _, in := range []string{"", "MiB", "1.5GiB", "12MB", "ten", "8388608TiB", "99999999999TiB", "-99999999999GiB"} {
+		if got, err := ParseByteSize(in); err == nil {
+			t.Errorf("ParseByteSize(%q) = %d, want an error", in, got)
+		}
+	}
+}
+
+func TestParseSweep(t *testing.T) {
+	tests := []struct {
+		expr        string
+		constraints []string
+		want        []string // Config names in expansion order
+	}{
+		{
+			expr: "GOMAXPROCS=1,2",
+			want: []string{"maxprocs-1", "maxprocs-2"},
+		},
+		{
+			expr: "GOGC=50..200:step50",
+			want: []string{"gc-50", "default", "gc-150", "gc-200"},
+		},
+		{
+			expr: "GOGC=25..200:x2,off",
+			want: []string{"gc-25", "gc-50", "default", "gc-200", "gc-off"},
+		},
+		{
+			expr: "GOMAXPROCS=1,default GOMEMLIMIT=off,256MiB",
+			want: []string{"maxprocs-1", "maxprocs-1_memlimit-256", "default", "memlimit-256"},
+		},
+		{
+			expr:        "GOMAXPROCS=1..4 GOGC=50,100",
+			constraints: []string{"GOMAXPROCS<=2", "GOGC=50 => GOMAXPROCS!=1"},
+			want:        []string{"maxprocs-1", "maxprocs-2_gc-50", "maxprocs-2"},
+		},
+		{
+			// Ordered comparisons never match an unset flag
+			expr:        "GOMEMLIMIT=off,128MiB,1GiB",
+			constraints: []string{"GOMEMLIMIT<512MiB"},
+			want:        []string{"memlimit-128"},
+		},
+		{
+			// Points that normalize to the same flags are emitted once
+			expr: "GOGC=100,100",
+			want: []string{"default"},
+		},
+	}
+	for _, tt := range tests {
+		sweep, err := ParseSweep(tt.expr, tt.constraints)
+		if err != nil {
+			t.Errorf("ParseSweep(%q, %q): %v", tt.expr, tt.constraints, err)
+			continue
+		}
+		got := []string{}
+		for _, cfg := range sweep.Expand() {
+			got = append(got, cfg.Name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSweep(%q, %q) expands to %v, want %v", tt.expr, tt.constraints, got, tt.want)
+		}
+	}
+}
+
+func TestParseSweepValues(t *testing.T) {
+	sweep, err := ParseSweep("gomaxprocs=2 GOMEMLIMIT=1GiB GOGC=off", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs := sweep.Expand()
+	want := BenchmarkConfig{Name: "maxprocs-2_memlimit-1024_gc-off", MaxProcs: 2, MemLimit: 1024, GCPercent: -1}
+	if len(configs) != 1 || configs[0] != want {
+		t.Errorf("Expand = %+v, want [%+v]", configs, want)
+	}
+	if sweep.Size() != 1 {
+		t.Errorf("Size = %d, want 1", sweep.Size())
+	}
+}
+
+func TestParseSweepErrors(t *testing.T) {
+	tests := []struct {
+		expr        string
+		constraints []string
+		wantErr     string
+	}{
+		{"", nil, "no flags"},
+		{"GOGC", nil, "expected KEY=values"},
+		{"GODEBUG=1", nil, "unknown flag"},
+		{"GOGC=50 GOGC=100", nil, "more than once"},
+		{"GOGC=50,", nil, "empty value"},
+		{"GOGC=200..100", nil, "below lower bound"},
+		{"GOGC=off..100", nil, "concrete values"},
+		{"GOGC=1..100:x1", nil, "invalid factor"},
+		{"GOGC=1..100:step0", nil, "invalid step"},
+		{"GOGC=1..100:by2", nil, "invalid range suffix"},
+		{"GOGC=1..5000", nil, "more than 1000 values"},
+		{"GOMAXPROCS=0", nil, "invalid GOMAXPROCS"},
+		{"GOMEMLIMIT=1000KiB", nil, "whole number of MiB"},
+		{"GOMEMLIMIT=99999999999TiB", nil, "does not fit"},
+		{"GOGC=100", []string{"GOGC~100"}, "expected a comparison"},
+		{"GOGC=100", []string{"GOTRACE<1"}, "unknown flag"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSweep(tt.expr, tt.constraints)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseSweep(%q, %q) error = %v, want one containing %q", tt.expr, tt.constraints, err, tt.wantErr)
+		}
+	}
+}
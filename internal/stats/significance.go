@@ -0,0 +1,207 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// MannWhitneyU returns the two-sided p-value of the Mann-Whitney U test
+// for the hypothesis that a and b come from the same distribution. It
+// makes no assumption about the shape of the distributions, which suits
+// run times with their long right tails. The exact distribution is used
+// for small samples without ties, the normal approximation otherwise.
+// It returns 1 if either sample is empty.
+func MannWhitneyU(a, b []float64) float64 {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	// Rank the pooled samples, giving ties their average rank
+	type obs struct {
+		v     float64
+		fromA bool
+	}
+	pooled := make([]obs, 0, n1+n2)
+	for _, v := range a {
+		pooled = append(pooled, obs{v, true})
+	}
+	for _, v := range b {
+		pooled = append(pooled, obs{v, false})
+	}
+	sort.Slice(pooled, func(i, j int) bool { return pooled[i].v < pooled[j].v })
+
+	rankSumA := 0.0
+	tieCorrection := 0.0
+	hasTies := false
+	for i := 0; i < len(pooled); {
+		j := i + 1
+		for j < len(pooled) && pooled[j].v == pooled[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if pooled[k].fromA {
+				rankSumA += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			hasTies = true
+			tieCorrection += t*t*t - t
+		}
+		i = j
+	}
+	u := rankSumA - float64(n1*(n1+1))/2
+
+	if !hasTies && n1 <= 50 && n2 <= 50 {
+		return exactUPValue(u, n1, n2)
+	}
+
+	n := float64(n1 + n2)
+	mu := float64(n1*n2) / 2
+	sigma := math.Sqrt(float64(n1*n2) / 12 * ((n + 1) - tieCorrection/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	// Continuity correction towards the mean
+	z := (math.Abs(u-mu) - 0.5) / sigma
+	if z < 0 {
+		z = 0
+	}
+	return math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// exactUPValue returns the two-sided p-value of U from the exact null
+// distribution, counting the orderings of n1+n2 distinct values that
+// give each U
+func exactUPValue(u float64, n1, n2 int) float64 {
+	// counts[m][n] is the number of orderings giving each U for samples
+	// of size m and n, built with f(m, n, u) = f(m-1, n, u-n) + f(m, n-1, u)
+	counts := make([][][]float64, n1+1)
+	for m := 0; m <= n1; m++ {
+		counts[m] = make([][]float64, n2+1)
+		for k := 0; k <= n2; k++ {
+			c := make([]float64, m*k+1)
+			if m == 0 || k == 0 {
+				c[0] = 1
+			} else {
+				for x := range c {
+					if x-k >= 0 && x-k < len(counts[m-1][k]) {
+						c[x] += counts[m-1][k][x-k]
+					}
+					if x < len(counts[m][k-1]) {
+						c[x] += counts[m][k-1][x]
+					}
+				}
+			}
+			counts[m][k] = c
+		}
+	}
+
+	dist := counts[n1][n2]
+	total := 0.0
+	for _, c := range dist {
+		total += c
+	}
+	// The distribution is symmetric, so fold U onto the lower tail
+	lower := math.Min(u, float64(n1*n2)-u)
+	tail := 0.0
+	for x := 0; float64(x) <= lower; x++ {
+		tail += dist[x]
+	}
+	return math.Min(1, 2*tail/total)
+}
+
+// WelchT returns the two-sided p-value of Welch's t-test for the
+// hypothesis that a and b have the same mean, without assuming equal
+// variances. It returns 1 if either sample has fewer than two values or
+// both have no variance.
+func WelchT(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 1
+	}
+	na, nb := float64(len(a)), float64(len(b))
+	ma, mb := mean(a), mean(b)
+	sa, sb := stdDev(a, ma), stdDev(b, mb)
+	va, vb := sa*sa/na, sb*sb/nb
+	if va+vb == 0 {
+		if ma == mb {
+			return 1
+		}
+		return 0
+	}
+
+	t := (ma - mb) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/(na-1) + vb*vb/(nb-1))
+	return regIncBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// regIncBeta is the regularized incomplete beta function I_x(a, b),
+// evaluated with its continued fraction expansion
+func regIncBeta(a, b, x float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	lbeta, _ := math.Lgamma(a + b)
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	front := math.Exp(lbeta - la - lb + a*math.Log(x) + b*math.Log(1-x))
+
+	// The expansion converges quickly only below the mean of the
+	// distribution; use the symmetry I_x(a, b) = 1 - I_{1-x}(b, a) above it
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+	}
+	return front * betaContinuedFraction(a, b, x) / a
+}
+
+// betaContinuedFraction evaluates the continued fraction of the
+// incomplete beta function with the modified Lentz method
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		// Even step
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		// Odd step
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
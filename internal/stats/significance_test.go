@@ -0,0 +1,73 @@
+package stats
+
+import "testing"
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"separated", []float64{1, 2, 3}, []float64{4, 5, 6}, 0.1},
+		{"separated, larger", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 2.0 / 252},
+		{"interleaved", []float64{1, 3, 5}, []float64{2, 4, 6}, 0.7},
+		{"identical", []float64{1, 2, 3}, []float64{1, 2, 3}, 1},
+		{"all tied", []float64{1, 1}, []float64{1, 1}, 1},
+		{"empty", nil, []float64{1, 2, 3}, 1},
+	}
+	for _, tt := range tests {
+		if got := MannWhitneyU(tt.a, tt.b); !approxEqual(got, tt.want, 1e-9) {
+			t.Errorf("%s: MannWhitneyU = %v, want %v", tt.name, got, tt.want)
+		}
+		if got, swapped := MannWhitneyU(tt.a, tt.b), MannWhitneyU(tt.b, tt.a); !approxEqual(got, swapped, 1e-12) {
+			t.Errorf("%s: MannWhitneyU not symmetric: %v and %v", tt.name, got, swapped)
+		}
+	}
+}
+
+func TestMannWhitneyUTies(t *testing.T) {
+	// Ties take the normal approximation with its tie correction
+	got := MannWhitneyU([]float64{1, 1, 2, 2}, []float64{3, 3, 4, 4})
+	if !approxEqual(got, 0.0265, 5e-4) {
+		t.Errorf("MannWhitneyU = %v, want about 0.0265", got)
+	}
+}
+
+func TestMannWhitneyULarge(t *testing.T) {
+	// Beyond 50 values per sample the normal approximation is used
+	a, b := make([]float64, 60), make([]float64, 60)
+	for i := range a {
+		a[i] = float64(2 * i)
+		b[i] = float64(2*i + 1)
+	}
+	if got := MannWhitneyU(a, b); got < 0.5 {
+		t.Errorf("MannWhitneyU of interleaved samples = %v, want no significance", got)
+	}
+	for i := range b {
+		b[i] += 200
+	}
+	if got := MannWhitneyU(a, b); got > 1e-6 {
+		t.Errorf("MannWhitneyU of separated samples = %v, want close to 0", got)
+	}
+}
+
+func TestWelchT(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		// t = -2 with 8 degrees of freedom
+		{"equal variances", []float64{1, 2, 3, 4, 5}, []float64{3, 4, 5, 6, 7}, 0.0805155},
+		{"unequal variances", []float64{1, 2, 3, 4, 5}, []float64{2, 4, 6, 8, 10, 12, 14}, 0.0227473},
+		{"identical", []float64{1, 2, 3}, []float64{1, 2, 3}, 1},
+		{"constant and equal", []float64{4, 4}, []float64{4, 4, 4}, 1},
+		{"constant and different", []float64{4, 4}, []float64{5, 5}, 0},
+		{"too few values", []float64{1}, []float64{2, 3}, 1},
+	}
+	for _, tt := range tests {
+		if got := WelchT(tt.a, tt.b); !approxEqual(got, tt.want, 1e-6) {
+			t.Errorf("%s: WelchT = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary describes the distribution of a set of samples
+type Summary struct {
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	StdDev float64 `json:"stddev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	CILow  float64 `json:"ci95_low"`  // Lower bound of the 95% confidence interval of the mean
+	CIHigh float64 `json:"ci95_high"` // Upper bound of the 95% confidence interval of the mean
+}
+
+// Summarize computes descriptive statistics for the given samples
+func Summarize(samples []float64) Summary {
+	s := Summary{N: len(samples)}
+	if s.N == 0 {
+		return s
+	}
+
+	sorted := make([]float64, len(samples))
+	copy(sorted, samples)
+	sort.Float64s(sorted)
+
+	s.Min = sorted[0]
+	s.Max = sorted[len(sorted)-1]
+	s.Median = median(sorted)
+	s.Mean = mean(sorted)
+	s.StdDev = stdDev(sorted, s.Mean)
+
+	// With a single sample there is no spread to estimate, so the
+	// interval collapses to the sample itself
+	margin := 0.0
+	if s.N > 1 {
+		margin = TCritical95(s.N-1) * s.StdDev / math.Sqrt(float64(s.N))
+	}
+	s.CILow = s.Mean - margin
+	s.CIHigh = s.Mean + margin
+
+	return s
+}
+
+// RelativeCI returns the half-width of the confidence interval as a
+// fraction of the mean, or 0 if the mean is 0
+func (s Summary) RelativeCI() float64 {
+	if s.Mean == 0 {
+		return 0
+	}
+	return (s.CIHigh - s.Mean) / math.Abs(s.Mean)
+}
+
+func mean(samples []float64) float64 {
+	sum := 0.0
+	for _, v := range samples {
+		sum += v
+	}
+	return sum / float64(len(samples))
+}
+
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// Percentile returns the p-th percentile (0-100) of the samples,
+// interpolating linearly between the closest ranks, or 0 if there are none
+func Percentile(samples []float64, p float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(samples))
+	copy(sorted, samples)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	if lo < 0 {
+		return sorted[0]
+	}
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := rank - float64(lo)
+	return sorted[lo] + frac*(sorted[lo+1]-sorted[lo])
+}
+
+// stdDev returns the sample (n-1) standard deviation
+func stdDev(samples []float64, mean float64) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range samples {
+		d := v - mean
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(samples)-1))
+}
+
+// Two-sided 95% critical values of Student's t distribution for 1-40
+// degrees of freedom
+var tTable95 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+	2.040, 2.037, 2.035, 2.032, 2.030, 2.028, 2.026, 2.024, 2.023, 2.021,
+}
+
+// TCritical95 returns the two-sided 95% critical value of Student's t
+// distribution for the given degrees of freedom. Beyond the table it
+// returns the value at the lower end of each range, which is never below
+// the exact one, so intervals err on the wide side.
+func TCritical95(df int) float64 {
+	switch {
+	case df <= 0:
+		return math.Inf(1)
+	case df <= len(tTable95):
+		return tTable95[df-1]
+	case df <= 60:
+		return 2.021 // df = 40
+	case df <= 120:
+		return 2.000 // df = 60
+	default:
+		return 1.980 // df = 120
+	}
+}
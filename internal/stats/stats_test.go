@@ -0,0 +1,113 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func approxEqual(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{9, 2, 4, 4, 5, 4, 7, 5})
+	if s.N != 8 || s.Min != 2 || s.Max != 9 || s.Mean != 5 || s.Median != 4.5 {
+		t.Errorf("Summarize = %+v, want N=8 Min=2 Max=9 Mean=5 Median=4.5", s)
+	}
+	wantSD := math.Sqrt(32.0 / 7)
+	if !approxEqual(s.StdDev, wantSD, 1e-12) {
+		t.Errorf("StdDev = %v, want %v", s.StdDev, wantSD)
+	}
+	margin := 2.365 * wantSD / math.Sqrt(8)
+	if !approxEqual(s.CILow, 5-margin, 1e-12) || !approxEqual(s.CIHigh, 5+margin, 1e-12) {
+		t.Errorf("CI = [%v, %v], want 5 ± %v", s.CILow, s.CIHigh, margin)
+	}
+}
+
+func TestSummarizeSmall(t *testing.T) {
+	if s := Summarize(nil); s != (Summary{}) {
+		t.Errorf("Summarize(nil) = %+v, want zero", s)
+	}
+	s := Summarize([]float64{3})
+	if s.Mean != 3 || s.StdDev != 0 || s.CILow != 3 || s.CIHigh != 3 {
+		t.Errorf("Summarize([3]) = %+v, want a collapsed interval at 3", s)
+	}
+	if got := s.RelativeCI(); got != 0 {
+		t.Errorf("RelativeCI = %v, want 0", got)
+	}
+}
+
+func TestSummarizeDoesNotReorderSamples(t *testing.T) {
+	samples := []float64{3, 1, 2}
+	Summarize(samples)
+	if samples[0] != 3 || samples[1] != 1 || samples[2] != 2 {
+		t.Errorf("samples reordered to %v", samples)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	samples := []float64{4, 1, 3, 2}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 1},
+		{50, 2.5},
+		{100, 4},
+		{-10, 1},
+		{150, 4},
+		{25, 1.75},
+	}
+	for _, tt := range tests {
+		if got := Percentile(samples, tt.p); !approxEqual(got, tt.want, 1e-12) {
+			t.Errorf("Percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("Percentile(nil) = %v, want 0", got)
+	}
+}
+
+func TestTCritical95(t *testing.T) {
+	tests := []struct {
+		df   int
+		want float64
+	}{
+		{1, 12.706},
+		{10, 2.228},
+		{30, 2.042},
+		{31, 2.040},
+		{40, 2.021},
+	}
+	for _, tt := range tests {
+		if got := TCritical95(tt.df); got != tt.want {
+			t.Errorf("TCritical95(%d) = %v, want %v", tt.df, got, tt.want)
+		}
+	}
+	if got := TCritical95(0); !math.IsInf(got, 1) {
+		t.Errorf("TCritical95(0) = %v, want +Inf", got)
+	}
+}
+
+// Beyond the table the critical value must never be below the exact one,
+// or confidence intervals would be too narrow
+func TestTCritical95Conservative(t *testing.T) {
+	exact := map[int]float64{
+		41:   2.0195,
+		50:   2.0086,
+		61:   1.9996,
+		100:  1.9840,
+		121:  1.9798,
+		1000: 1.9623,
+	}
+	for df, want := range exact {
+		if got := TCritical95(df); got < want {
+			t.Errorf("TCritical95(%d) = %v, below the exact %v", df, got, want)
+		}
+	}
+	for df := 2; df <= 1000; df++ {
+		if TCritical95(df) > TCritical95(df-1) {
+			t.Errorf("TCritical95(%d) = %v > TCritical95(%d) = %v", df, TCritical95(df), df-1, TCritical95(df-1))
+		}
+	}
+}